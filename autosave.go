@@ -0,0 +1,158 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	defaultAutosaveKeep     = 10
+	autosaveTimestampFormat = "20060102-150405"
+)
+
+// AutosaveDir returns the directory holding the history of autosaved
+// snapshots, one directory per run named after its timestamp.
+func AutosaveDir() string {
+	return filepath.Join(StateDir(), "autosave")
+}
+
+// SmugSessions returns the running sessions created by smug.
+func (smug Smug) SmugSessions() ([]string, error) {
+	// list-sessions fails when no tmux server is running, there are no
+	// sessions then
+	sessions, err := smug.tmux.ListSessions()
+	if err != nil {
+		return nil, nil
+	}
+
+	var managed []string
+	for _, session := range sessions {
+		// The session may have closed since it was listed
+		env, err := smug.tmux.ShowEnvironment(session)
+		if err != nil {
+			continue
+		}
+
+		if _, ok := env["SMUG_SESSION"]; ok {
+			managed = append(managed, session)
+		}
+	}
+
+	return managed, nil
+}
+
+// Autosave saves every running smug session into a new snapshot under dir
+// and removes the oldest snapshots so at most keep of them remain.
+func (smug Smug) Autosave(dir string, now time.Time, scrollback int, keep int) ([]string, error) {
+	sessions, err := smug.SmugSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(sessions) == 0 {
+		return nil, nil
+	}
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		return nil, err
+	}
+
+	// The run is written aside and only becomes the latest snapshot once
+	// every session is saved
+	name := now.Format(autosaveTimestampFormat)
+	tmp, err := os.MkdirTemp(dir, "."+name+"-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	for _, session := range sessions {
+		err := smug.SaveSession(session, filepath.Join(tmp, sessionFileName(session)), scrollback)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := os.Chmod(tmp, 0o750); err != nil {
+		return nil, err
+	}
+
+	if err := replaceDir(tmp, filepath.Join(dir, name)); err != nil {
+		return nil, err
+	}
+
+	if keep <= 0 {
+		keep = defaultAutosaveKeep
+	}
+
+	return sessions, rotateSnapshots(dir, keep)
+}
+
+// listSnapshots returns the snapshots in dir from the oldest to the newest.
+func listSnapshots(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var snapshots []string
+	for _, e := range entries {
+		if _, err := time.Parse(autosaveTimestampFormat, e.Name()); e.IsDir() && err == nil {
+			snapshots = append(snapshots, e.Name())
+		}
+	}
+
+	// The timestamp format sorts chronologically
+	slices.Sort(snapshots)
+
+	return snapshots, nil
+}
+
+func rotateSnapshots(dir string, keep int) error {
+	snapshots, err := listSnapshots(dir)
+	if err != nil {
+		return err
+	}
+
+	for len(snapshots) > keep {
+		if err := os.RemoveAll(filepath.Join(dir, snapshots[0])); err != nil {
+			return err
+		}
+		snapshots = snapshots[1:]
+	}
+
+	return nil
+}
+
+// LatestSnapshot returns the session snapshots of the most recent autosave
+// in dir.
+func LatestSnapshot(dir string) ([]string, error) {
+	snapshots, err := listSnapshots(dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+
+	if len(snapshots) == 0 {
+		return nil, errors.New("no autosaved sessions found")
+	}
+
+	latest := filepath.Join(dir, snapshots[len(snapshots)-1])
+	entries, err := os.ReadDir(latest)
+	if err != nil {
+		return nil, err
+	}
+
+	var sessions []string
+	for _, e := range entries {
+		// Skip the leftovers of an interrupted save
+		if e.IsDir() && !strings.HasPrefix(e.Name(), ".") {
+			sessions = append(sessions, filepath.Join(latest, e.Name()))
+		}
+	}
+
+	return sessions, nil
+}
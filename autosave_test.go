@@ -0,0 +1,138 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestAutosave(t *testing.T) {
+	procDir = t.TempDir()
+	defer func() { procDir = "/proc" }()
+
+	dir := t.TempDir()
+	for _, old := range []string{"20260101-100000", "20260102-100000"} {
+		if err := os.MkdirAll(filepath.Join(dir, old, "blog"), 0o750); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	commander := &MockCommander{[]string{}, []string{
+		"blog\nscratch",
+		"SMUG_SESSION=blog",
+		"FOO=bar",
+		"SMUG_SESSION=blog",
+		"",
+		"@1;code;layout;1;/home/user/blog",
+		"%1;0;0;101;bash;/home/user/blog;",
+	}}
+	tmux := Tmux{commander, &TmuxOptions{}}
+	smug := Smug{tmux, commander}
+
+	now := time.Date(2026, 1, 3, 10, 0, 0, 0, time.UTC)
+	sessions, err := smug.Autosave(dir, now, 0, 2)
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+
+	if !reflect.DeepEqual([]string{"blog"}, sessions) {
+		t.Errorf("expected only the smug session to be saved, got %v", sessions)
+	}
+
+	snapshots, err := listSnapshots(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"20260102-100000", "20260103-100000"}
+	if !reflect.DeepEqual(expected, snapshots) {
+		t.Errorf("expected snapshots %v, got %v", expected, snapshots)
+	}
+
+	latest, err := LatestSnapshot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = []string{filepath.Join(dir, "20260103-100000", "blog")}
+	if !reflect.DeepEqual(expected, latest) {
+		t.Errorf("expected latest %v, got %v", expected, latest)
+	}
+}
+
+func TestLatestSnapshotWithoutAutosaves(t *testing.T) {
+	_, err := LatestSnapshot(filepath.Join(t.TempDir(), "missing"))
+	if err == nil {
+		t.Fatal("expected error when there are no autosaves")
+	}
+}
+
+func TestAutosaveWithoutServer(t *testing.T) {
+	dir := t.TempDir()
+
+	commander := &failingCommander{failCommand: "tmux list-sessions"}
+	smug := Smug{Tmux{commander, &TmuxOptions{}}, commander}
+
+	saved, err := smug.Autosave(dir, time.Now(), 0, 10)
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+	if len(saved) != 0 {
+		t.Errorf("expected no saved sessions, got %v", saved)
+	}
+}
+
+func TestAutosaveFailureKeepsLatest(t *testing.T) {
+	dir := t.TempDir()
+	previous := filepath.Join(dir, "20260102-100000", "blog")
+	if err := os.MkdirAll(previous, 0o750); err != nil {
+		t.Fatal(err)
+	}
+
+	commander := &failingCommander{
+		running:     []string{"blog", "web"},
+		failCommand: "tmux show-hooks -t web",
+		outputs:     map[string]string{"tmux show-environment": "SMUG_SESSION=x"},
+	}
+	smug := Smug{Tmux{commander, &TmuxOptions{}}, commander}
+
+	now := time.Date(2026, 1, 3, 10, 0, 0, 0, time.UTC)
+	if _, err := smug.Autosave(dir, now, 0, 10); err == nil {
+		t.Fatal("expected error when a session can't be saved")
+	}
+
+	latest, err := LatestSnapshot(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual([]string{previous}, latest) {
+		t.Errorf("expected the previous snapshot to stay the latest, got %v", latest)
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected the failed run to be removed, got %v", entries)
+	}
+}
+
+func TestSmugSessionsSkipsClosedSessions(t *testing.T) {
+	commander := &failingCommander{
+		running:     []string{"blog", "gone"},
+		failCommand: "tmux show-environment -t gone",
+		outputs:     map[string]string{"tmux show-environment": "SMUG_SESSION=blog"},
+	}
+	smug := Smug{Tmux{commander, &TmuxOptions{}}, commander}
+
+	sessions, err := smug.SmugSessions()
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+	if !reflect.DeepEqual([]string{"blog"}, sessions) {
+		t.Errorf("expected the closed session to be skipped, got %v", sessions)
+	}
+}
@@ -9,20 +9,20 @@ _smug() {
     # if command is 'list' or 'print' do not suggest more
     for word in ${COMP_WORDS[@]}; do
         case $word in
-            list|print|rm) return
+            last|list|pick|print|rm) return
         esac
     done
 
     # commands
     if (( "${#COMP_WORDS[@]}" == 2 )); then
-        reply=($(compgen -W "list print rm start stop switch" -- "${cur}"))
+        reply=($(compgen -W "last list menu pick print rm start stop switch worktrees" -- "${cur}"))
     fi
 
     # projects
     if (( "${#COMP_WORDS[@]}" == 3 )); then
         case ${prev} in
-            start|stop|rm|switch)
-                reply=($(compgen -W "$(smug list | grep -F -v smug)" -- "${cur}"))
+            start|stop|rm|switch|worktrees)
+                reply=($(compgen -W "$({ smug list --recent; smug list; } | awk '$1 != "NAME" {print $1; sub("/.*", "", $1); print $1}' | awk '!seen[$0]++')" -- "${cur}"))
         esac
     fi
 
@@ -68,4 +68,5 @@ _smug() {
     fi
 }
 
-complete -F _smug smug
+# Projects are suggested by frecency, nosort keeps that order
+complete -o nosort -F _smug smug 2>/dev/null || complete -F _smug smug
@@ -1,3 +1,7 @@
-complete -x -c smug -a "(ls ~/.config/smug | grep -v \"smug\.log\" | sed -e 's/\..*//')"
+complete -k -x -c smug -a "(begin; smug list --recent; smug list; end | awk '\$1 != \"NAME\" {print \$1; sub(\"/.*\", \"\", \$1); print \$1}' | awk '!seen[\$0]++')"
 complete -c smug -n '__fish_use_subcommand' -a 'rm' -d 'Remove project configuration'
 complete -c smug -n '__fish_use_subcommand' -a 'switch' -d 'Switch to a project session'
+complete -c smug -n '__fish_use_subcommand' -a 'pick' -d 'Pick a project or a session with a fuzzy finder'
+complete -c smug -n '__fish_use_subcommand' -a 'menu' -d 'Show a tmux menu of the projects'
+complete -c smug -n '__fish_use_subcommand' -a 'last' -d 'Switch to the previously used project'
+complete -c smug -n '__fish_use_subcommand' -a 'worktrees' -d 'List the git worktrees of a project'
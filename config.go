@@ -1,18 +1,27 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"os"
 	"os/exec"
 	"os/user"
 	"path"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
+const (
+	FormatYAML = "yaml"
+	FormatJSON = "json"
+)
+
 type ConfigNotFoundError struct {
 	Project string
 }
@@ -22,42 +31,98 @@ func (e ConfigNotFoundError) Error() string {
 }
 
 type Pane struct {
-	Root     string   `yaml:"root,omitempty"`
-	Type     string   `yaml:"type,omitempty"`
-	Commands []string `yaml:"commands"`
+	Root     string   `yaml:"root,omitempty" json:"root,omitempty"`
+	Type     string   `yaml:"type,omitempty" json:"type,omitempty"`
+	Commands []string `yaml:"commands" json:"commands"`
 }
 
 type Window struct {
-	Selected    bool     `yaml:"selected"`
-	Name        string   `yaml:"name"`
-	Root        string   `yaml:"root,omitempty"`
-	BeforeStart []string `yaml:"before_start"`
-	Panes       []Pane   `yaml:"panes"`
-	Commands    []string `yaml:"commands"`
-	Layout      string   `yaml:"layout"`
-	Manual      bool     `yaml:"manual,omitempty"`
+	Selected    bool     `yaml:"selected" json:"selected"`
+	Name        string   `yaml:"name" json:"name"`
+	Root        string   `yaml:"root,omitempty" json:"root,omitempty"`
+	BeforeStart []string `yaml:"before_start" json:"before_start"`
+	Panes       []Pane   `yaml:"panes" json:"panes"`
+	Commands    []string `yaml:"commands" json:"commands"`
+	Layout      string   `yaml:"layout" json:"layout"`
+	Manual      bool     `yaml:"manual,omitempty" json:"manual,omitempty"`
 }
 
 type Config struct {
-	SendKeysTimeout int    `yaml:"sendkeys_timeout"`
-	Session         string `yaml:"session"`
-	DetachHook      string `yaml:"detach_hook"`
-	AttachHook      string `yaml:"attach_hook"`
+	SendKeysTimeout int    `yaml:"sendkeys_timeout" json:"sendkeys_timeout"`
+	Session         string `yaml:"session" json:"session"`
+	DetachHook      string `yaml:"detach_hook" json:"detach_hook"`
+	AttachHook      string `yaml:"attach_hook" json:"attach_hook"`
 
 	// Attach controls whether the session automatically attaches after creation.
 	// The -a/--attach CLI flag can also enable attachment.
-	Attach      bool `yaml:"attach,omitempty"`
-	TmuxOptions `yaml:"tmux_options"`
-	Env         map[string]string `yaml:"env"`
-	Root        string            `yaml:"root"`
-	BeforeStart []string          `yaml:"before_start"`
-	Stop        []string          `yaml:"stop"`
-	Windows     []Window          `yaml:"windows"`
+	Attach      bool `yaml:"attach,omitempty" json:"attach,omitempty"`
+	TmuxOptions `yaml:"tmux_options" json:"tmux_options"`
+	Env         map[string]string `yaml:"env" json:"env"`
+	Root        string            `yaml:"root" json:"root"`
+	BeforeStart []string          `yaml:"before_start" json:"before_start"`
+	Stop        []string          `yaml:"stop" json:"stop"`
+	Windows     []Window          `yaml:"windows" json:"windows"`
+
+	// Requires names the projects whose sessions must run before this one
+	Requires []string `yaml:"requires,omitempty" json:"requires,omitempty"`
+
+	Description string   `yaml:"description,omitempty" json:"description,omitempty"`
+	Tags        []string `yaml:"tags,omitempty" json:"tags,omitempty"`
+	// Aliases are other names of the project for smug start and the other
+	// commands taking a project
+	Aliases []string `yaml:"aliases,omitempty" json:"aliases,omitempty"`
+	// WorktreePath is where --create adds git worktrees, see WorktreePath
+	WorktreePath string `yaml:"worktree_path,omitempty" json:"worktree_path,omitempty"`
 }
 
 func addDefaultEnvs(c *Config, path string) {
 	c.Env["SMUG_SESSION"] = c.Session
-	c.Env["SMUG_SESSION_CONFIG_PATH"] = path
+	if path != StdinConfigPath {
+		c.Env["SMUG_SESSION_CONFIG_PATH"] = path
+	}
+}
+
+// UserConfigDir returns the directory of the user's configs,
+// $XDG_CONFIG_HOME/smug or ~/.config/smug.
+func UserConfigDir() string {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, "smug")
+	}
+
+	return filepath.Join(ExpandPath("~/"), ".config/smug")
+}
+
+// ConfigDirs returns the directories searched for project configs: the user
+// config dir followed by the colon-separated directories of $SMUG_PATH.
+func ConfigDirs(userConfigDir string) []string {
+	dirs := []string{userConfigDir}
+	for _, dir := range filepath.SplitList(os.Getenv("SMUG_PATH")) {
+		dir = ExpandPath(dir)
+		if dir != "" && !slices.Contains(dirs, dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+
+	return dirs
+}
+
+// FindLocalConfig looks for a local config file in dir and its parents, the
+// way git looks for the repository.
+func FindLocalConfig(dir string) (string, error) {
+	for {
+		for _, name := range localConfigFiles {
+			path := filepath.Join(dir, name)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path, nil
+			}
+		}
+
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("%s not found in the current directory or any of its parents", localConfigFiles[0])
+		}
+		dir = parent
+	}
 }
 
 func RemoveConfig(path string) error {
@@ -96,8 +161,55 @@ func setTmuxOptions(tmuxOpts *TmuxOptions, c Config) {
 	}
 }
 
+// instanceSeparator separates the session of a config from the name of an
+// instance, as in blog@review.
+const instanceSeparator = "@"
+
+// SplitInstance splits a project given as project@instance.
+func SplitInstance(project string) (string, string) {
+	if i := strings.LastIndex(project, instanceSeparator); i > 0 {
+		return project[:i], project[i+1:]
+	}
+
+	return project, ""
+}
+
+// ApplyInstance renames the session of the config to session, if set, and
+// then to <session>@<instance> for an instance, so the same config can run
+// several times side by side.
+func ApplyInstance(config *Config, session string, instance string) {
+	if session != "" {
+		config.Session = session
+	}
+
+	if instance != "" {
+		config.Session += instanceSeparator + instance
+		config.Env["SMUG_INSTANCE"] = instance
+	}
+
+	config.Env["SMUG_SESSION"] = config.Session
+}
+
+// StdinConfigPath is the config path reading the config from stdin.
+const StdinConfigPath = "-"
+
+var configExtensions = []string{".yml", ".yaml", ".json"}
+
+// localConfigFiles are the names of the configs looked up from the current
+// directory when no project is given.
+var localConfigFiles = []string{".smug.yml", ".smug.yaml"}
+
+// GetConfig reads the config at path, or from stdin if path is "-". JSON
+// configs are read the same way as YAML ones, since YAML is a superset of
+// JSON.
 func GetConfig(path string, settings map[string]string, tmuxOpts *TmuxOptions) (*Config, error) {
-	f, err := os.ReadFile(path)
+	var f []byte
+	var err error
+	if path == StdinConfigPath {
+		f, err = io.ReadAll(os.Stdin)
+	} else {
+		f, err = os.ReadFile(path)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -115,6 +227,18 @@ func GetConfig(path string, settings map[string]string, tmuxOpts *TmuxOptions) (
 	return &c, err
 }
 
+// MarshalConfig encodes the config in the given format, yaml or json.
+func MarshalConfig(config Config, format string) ([]byte, error) {
+	switch format {
+	case "", FormatYAML:
+		return yaml.Marshal(&config)
+	case FormatJSON:
+		return json.MarshalIndent(&config, "", "  ")
+	}
+
+	return nil, fmt.Errorf("unsupported format %q", format)
+}
+
 func ParseConfig(data string, settings map[string]string) (Config, error) {
 	data = os.Expand(data, func(v string) string {
 		if val, ok := settings[v]; ok {
@@ -148,12 +272,17 @@ func ListConfigs(dir string, includeDirs bool) ([]string, error) {
 	}
 
 	for _, file := range files {
+		// Hidden files, like the manifest of a group, are not configs
+		if strings.HasPrefix(file.Name(), ".") {
+			continue
+		}
+
 		fileExt := path.Ext(file.Name())
 		dirCheck := true
 		if includeDirs {
 			dirCheck = !file.IsDir()
 		}
-		if fileExt != ".yml" && fileExt != ".yaml" && dirCheck {
+		if !slices.Contains(configExtensions, fileExt) && dirCheck {
 			continue
 		}
 		result = append(result, file.Name())
@@ -162,6 +291,25 @@ func ListConfigs(dir string, includeDirs bool) ([]string, error) {
 	return result, nil
 }
 
+// FindConfigPath returns the path of the config file of the project in the
+// first of dirs holding one.
+func FindConfigPath(dirs []string, project string) (string, error) {
+	for _, dir := range dirs {
+		config, err := FindConfig(dir, project)
+		var notFound ConfigNotFoundError
+		if errors.As(err, &notFound) || errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+
+		return filepath.Join(dir, config), nil
+	}
+
+	return "", ConfigNotFoundError{Project: project}
+}
+
 func FindConfig(dir, project string) (string, error) {
 	configs, err := ListConfigs(dir, false)
 	if err != nil {
@@ -175,44 +323,158 @@ func FindConfig(dir, project string) (string, error) {
 		}
 	}
 
-	return "", ConfigNotFoundError{Project: project}
+	// Aliases are only looked up when no config is named after the project
+	var matches []string
+	for _, config := range configs {
+		metadata, err := readConfigMetadata(filepath.Join(dir, config))
+		if err == nil && slices.Contains(metadata.Aliases, project) {
+			matches = append(matches, config)
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return "", ConfigNotFoundError{Project: project}
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("alias %s is ambiguous, it names %s", project, strings.Join(matches, ", "))
+	}
 }
 
-func FindConfigs(dir, project string) ([]string, error) {
-	isDir, _ := IsDirectory(dir + "/" + project)
+// configMetadata are the keys of a config describing the project.
+type configMetadata struct {
+	Tags    []string `yaml:"tags"`
+	Aliases []string `yaml:"aliases"`
+}
 
-	if isDir {
-		configs, err := ListConfigs(dir+"/"+project, false)
-		if err != nil {
-			return configs, err
+// readConfigMetadata reads the metadata of the config at path, without
+// expanding the variables of the config.
+func readConfigMetadata(path string) (configMetadata, error) {
+	metadata := configMetadata{}
+
+	f, err := os.ReadFile(path)
+	if err != nil {
+		return metadata, err
+	}
+
+	err = yaml.Unmarshal(f, &metadata)
+	return metadata, err
+}
+
+// FindTaggedProjects returns the projects of the config dirs carrying any of
+// the tags.
+func FindTaggedProjects(dirs []string, tags []string) ([]string, error) {
+	projects, err := ProjectConfigs(dirs)
+	if err != nil {
+		return nil, err
+	}
+
+	var tagged []string
+	for _, p := range projects {
+		if p.Err != nil {
+			continue
 		}
 
-		for configIndex, configName := range configs {
-			configs[configIndex] = dir + "/" + project + "/" + configName
+		metadata, err := readConfigMetadata(p.Path)
+		if err != nil {
+			continue
 		}
 
-		return nil, err
+		if slices.ContainsFunc(metadata.Tags, func(tag string) bool { return slices.Contains(tags, tag) }) {
+			tagged = append(tagged, p.Name)
+		}
 	}
 
-	configs, err := ListConfigs(dir, false)
-	if err != nil {
-		return nil, fmt.Errorf("list configs %w", err)
+	if len(tagged) == 0 {
+		return nil, fmt.Errorf("no project is tagged %s", strings.Join(tags, " or "))
 	}
 
-	if len(configs) == 0 {
-		return nil, ConfigNotFoundError{Project: project}
+	return tagged, nil
+}
+
+// MergeTaggedProjects appends the tagged projects to the given ones, except
+// the projects whose configs are already started or stopped, by name, by
+// alias or as members of a group.
+func MergeTaggedProjects(dirs []string, projects []string, tagged []string) []string {
+	var merged []string
+	covered := map[string]bool{}
+
+	for _, project := range append(slices.Clone(projects), tagged...) {
+		configs, err := FindConfigs(dirs, project)
+		if err != nil {
+			// Reported when the project is started
+			if !slices.Contains(merged, project) {
+				merged = append(merged, project)
+			}
+			continue
+		}
+
+		added := false
+		for _, config := range configs {
+			if !covered[filepath.Clean(config)] {
+				covered[filepath.Clean(config)] = true
+				added = true
+			}
+		}
+		if added {
+			merged = append(merged, project)
+		}
 	}
 
-	for _, config := range configs {
-		fileExt := path.Ext(config)
-		if strings.TrimSuffix(config, fileExt) == project {
-			return []string{dir + "/" + config}, nil
+	return merged
+}
+
+// FindConfigs returns the configs of the project from the first directory of
+// dirs that has it.
+func FindConfigs(dirs []string, project string) ([]string, error) {
+	for _, dir := range dirs {
+		configs, err := findConfigsInDir(dir, project)
+		var notFound ConfigNotFoundError
+		if errors.As(err, &notFound) || errors.Is(err, os.ErrNotExist) {
+			continue
 		}
+
+		return configs, err
 	}
 
 	return nil, ConfigNotFoundError{Project: project}
 }
 
+func findConfigsInDir(dir, project string) ([]string, error) {
+	isDir, _ := IsDirectory(dir + "/" + project)
+
+	if isDir {
+		group, err := LoadGroup(dir + "/" + project)
+		if err != nil {
+			return nil, err
+		}
+
+		return group.Members, nil
+	}
+
+	// A single member of a group is addressed as group/member
+	if group, member, ok := strings.Cut(project, "/"); ok {
+		config, err := FindConfig(dir+"/"+group, member)
+		var notFound ConfigNotFoundError
+		if errors.As(err, &notFound) || errors.Is(err, os.ErrNotExist) {
+			return nil, ConfigNotFoundError{Project: project}
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		return []string{dir + "/" + group + "/" + config}, nil
+	}
+
+	config, err := FindConfig(dir, project)
+	if err != nil {
+		return nil, err
+	}
+
+	return []string{dir + "/" + config}, nil
+}
+
 func IsDirectory(path string) (bool, error) {
 	fileInfo, err := os.Stat(path)
 	if os.IsNotExist(err) {
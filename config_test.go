@@ -1,7 +1,9 @@
 package main
 
 import (
+	"errors"
 	"os"
+	"path/filepath"
 	"reflect"
 	"testing"
 )
@@ -95,3 +97,304 @@ windows:
 		t.Fatal("expected attach to be false by default, got true")
 	}
 }
+
+func TestParseJSONConfig(t *testing.T) {
+	json := `{
+	"session": "${session}",
+	"root": "~/code",
+	"env": {"PORT": 8080},
+	"windows": [
+		{"name": "code", "commands": ["vim"]}
+	]
+}`
+
+	config, err := ParseConfig(json, map[string]string{"session": "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Config{
+		Session: "test",
+		Root:    "~/code",
+		Env:     map[string]string{"PORT": "8080"},
+		Windows: []Window{
+			{Name: "code", Commands: []string{"vim"}},
+		},
+	}
+
+	if !reflect.DeepEqual(expected, config) {
+		t.Fatalf("expected %v, got %v", expected, config)
+	}
+}
+
+func TestListConfigs(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"blog.yml", "api.yaml", "web.json", "notes.txt", "smug.log"} {
+		if err := os.WriteFile(filepath.Join(dir, file), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	configs, err := ListConfigs(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"api.yaml", "blog.yml", "web.json"}
+	if !reflect.DeepEqual(expected, configs) {
+		t.Errorf("expected %v, got %v", expected, configs)
+	}
+
+	config, err := FindConfig(dir, "web")
+	if err != nil || config != "web.json" {
+		t.Errorf("expected web.json, got %q (%v)", config, err)
+	}
+}
+
+func TestGetConfigFromStdin(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+
+	if _, err := w.WriteString(`{"session": "piped", "windows": [{"name": "code"}]}`); err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+
+	config, err := GetConfig("-", map[string]string{}, &TmuxOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if config.Session != "piped" || len(config.Windows) != 1 {
+		t.Errorf("unexpected config %v", config)
+	}
+
+	if _, ok := config.Env["SMUG_SESSION_CONFIG_PATH"]; ok {
+		t.Errorf("expected no config path for a config read from stdin")
+	}
+}
+
+func TestConfigDirs(t *testing.T) {
+	t.Setenv("SMUG_PATH", "/srv/smug:/home/smug:/srv/smug::")
+
+	dirs := ConfigDirs("/home/smug")
+	expected := []string{"/home/smug", "/srv/smug"}
+	if !reflect.DeepEqual(expected, dirs) {
+		t.Errorf("expected %v, got %v", expected, dirs)
+	}
+}
+
+func TestFindConfigsInDirs(t *testing.T) {
+	user := t.TempDir()
+	shared := t.TempDir()
+	for _, file := range []string{
+		filepath.Join(user, "blog.yml"),
+		filepath.Join(shared, "blog.yml"),
+		filepath.Join(shared, "api.yml"),
+	} {
+		if err := os.WriteFile(file, []byte("session: test\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dirs := []string{user, filepath.Join(user, "missing"), shared}
+
+	configs, err := FindConfigs(dirs, "blog")
+	if err != nil || !reflect.DeepEqual([]string{filepath.Join(user, "blog.yml")}, configs) {
+		t.Errorf("expected the user blog config, got %v (%v)", configs, err)
+	}
+
+	configs, err = FindConfigs(dirs, "api")
+	if err != nil || !reflect.DeepEqual([]string{filepath.Join(shared, "api.yml")}, configs) {
+		t.Errorf("expected the shared api config, got %v (%v)", configs, err)
+	}
+
+	if _, err := FindConfigs(dirs, "web"); !errors.As(err, &ConfigNotFoundError{}) {
+		t.Errorf("expected ConfigNotFoundError, got %v", err)
+	}
+
+	path, err := FindConfigPath(dirs, "api")
+	if err != nil || path != filepath.Join(shared, "api.yml") {
+		t.Errorf("expected the shared api config path, got %q (%v)", path, err)
+	}
+
+	if _, err := FindConfigPath(dirs, "web"); !errors.As(err, &ConfigNotFoundError{}) {
+		t.Errorf("expected ConfigNotFoundError, got %v", err)
+	}
+}
+
+func TestFindLocalConfig(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "src", "app")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := FindLocalConfig(nested); err == nil {
+		t.Errorf("expected an error without a local config")
+	}
+
+	config := filepath.Join(root, ".smug.yaml")
+	if err := os.WriteFile(config, []byte("session: test\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	found, err := FindLocalConfig(nested)
+	if err != nil || found != config {
+		t.Errorf("expected %s, got %q (%v)", config, found, err)
+	}
+}
+
+func TestFindConfigByAlias(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"frontend-monorepo.yml": "session: frontend\naliases: [fe, web]\n",
+		"fe.yml":                "session: fe\n",
+		"website.yml":           "session: website\naliases: [web]\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// A config named after the project wins over aliases
+	if config, err := FindConfig(dir, "fe"); err != nil || config != "fe.yml" {
+		t.Errorf("expected fe.yml, got %q (%v)", config, err)
+	}
+
+	if err := os.Remove(filepath.Join(dir, "fe.yml")); err != nil {
+		t.Fatal(err)
+	}
+
+	configs, err := FindConfigs([]string{dir}, "fe")
+	if err != nil || !reflect.DeepEqual([]string{dir + "/frontend-monorepo.yml"}, configs) {
+		t.Errorf("expected frontend-monorepo.yml, got %v (%v)", configs, err)
+	}
+
+	if _, err := FindConfig(dir, "web"); err == nil {
+		t.Errorf("expected an error for an ambiguous alias")
+	}
+}
+
+func TestFindTaggedProjects(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"api.yml":            "session: api\ntags: [backend, work]\n",
+		"blog.yml":           "session: blog\ntags: [personal]\n",
+		"web.yml":            "session: web\ntags: [frontend, work]\n",
+		"backend/worker.yml": "session: worker\ntags: [backend]\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	projects, err := FindTaggedProjects([]string{dir}, []string{"backend", "frontend"})
+	expected := []string{"api", "backend/worker", "web"}
+	if err != nil || !reflect.DeepEqual(expected, projects) {
+		t.Errorf("expected %v, got %v (%v)", expected, projects, err)
+	}
+
+	if _, err := FindTaggedProjects([]string{dir}, []string{"games"}); err == nil {
+		t.Errorf("expected an error without tagged projects")
+	}
+}
+
+func TestSplitInstance(t *testing.T) {
+	cases := []struct {
+		project  string
+		name     string
+		instance string
+	}{
+		{"blog", "blog", ""},
+		{"blog@review", "blog", "review"},
+		{"backend/api@hotfix", "backend/api", "hotfix"},
+		{"me@host@2", "me@host", "2"},
+		{"@blog", "@blog", ""},
+	}
+
+	for _, c := range cases {
+		name, instance := SplitInstance(c.project)
+		if name != c.name || instance != c.instance {
+			t.Errorf("%s: expected %q %q, got %q %q", c.project, c.name, c.instance, name, instance)
+		}
+	}
+}
+
+func TestApplyInstance(t *testing.T) {
+	cases := []struct {
+		session  string
+		instance string
+		expected string
+	}{
+		{"", "", "blog"},
+		{"", "review", "blog@review"},
+		{"notes", "", "notes"},
+		{"notes", "review", "notes@review"},
+	}
+
+	for _, c := range cases {
+		config := &Config{Session: "blog", Env: map[string]string{"SMUG_SESSION": "blog"}}
+		ApplyInstance(config, c.session, c.instance)
+
+		if config.Session != c.expected || config.Env["SMUG_SESSION"] != c.expected {
+			t.Errorf("expected session %s, got %s (env %s)", c.expected, config.Session, config.Env["SMUG_SESSION"])
+		}
+		if config.Env["SMUG_INSTANCE"] != c.instance {
+			t.Errorf("expected SMUG_INSTANCE %q, got %q", c.instance, config.Env["SMUG_INSTANCE"])
+		}
+	}
+}
+
+func TestMergeTaggedProjects(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"api.yml":            "session: api\ntags: [work]\naliases: [backend-api]\n",
+		"web.yml":            "session: web\ntags: [work]\n",
+		"backend/worker.yml": "session: worker\ntags: [work]\n",
+		"backend/db.yml":     "session: db\n",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tagged, err := FindTaggedProjects([]string{dir}, []string{"work"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		projects []string
+		expected []string
+	}{
+		{[]string{"api"}, []string{"api", "backend/worker", "web"}},
+		{[]string{"backend-api", "api"}, []string{"backend-api", "backend/worker", "web"}},
+		{[]string{"backend"}, []string{"backend", "api", "web"}},
+		{[]string{"missing", "missing"}, []string{"missing", "api", "backend/worker", "web"}},
+	}
+
+	for _, c := range cases {
+		merged := MergeTaggedProjects([]string{dir}, c.projects, tagged)
+		if !reflect.DeepEqual(c.expected, merged) {
+			t.Errorf("%v: expected %v, got %v", c.projects, c.expected, merged)
+		}
+	}
+}
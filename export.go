@@ -0,0 +1,255 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	FormatShell = "sh"
+	FormatTmux  = "tmux"
+)
+
+// scriptVariable matches the shell variables holding the ids of the windows
+// and panes created by an exported script.
+var scriptVariable = regexp.MustCompile(`\$\{smug_[a-z]+\d+\}`)
+
+// scriptCommander records the commands run by Smug.Start instead of running
+// them, and renders them as a shell script or a tmux command file.
+type scriptCommander struct {
+	format          string
+	sendKeysTimeout int
+	lines           []string
+	windows         int
+	panes           int
+}
+
+func (c *scriptCommander) Exec(cmd *exec.Cmd) (string, error) {
+	if cmd.Args[0] != "tmux" {
+		c.shell(cmd)
+		return "", nil
+	}
+
+	global, args := splitTmuxArgs(cmd.Args)
+
+	switch args[0] {
+	case "list-sessions", "display-message":
+		// Queries about the running sessions: there are none yet when the
+		// script runs
+		return "", nil
+	case "new":
+		c.tmux(global, withoutPrintFlag(args))
+		return "", nil
+	case "neww":
+		if c.format == FormatTmux {
+			c.tmux(global, withoutPrintFlag(args))
+			return flagValue(args, "-t") + flagValue(args, "-n"), nil
+		}
+
+		c.windows++
+		return c.capture(global, args, "w", c.windows), nil
+	case "split-window":
+		if c.format == FormatTmux {
+			// The new pane is always right after the active one, since
+			// panes are split without changing the active pane
+			c.tmux(global, withoutPrintFlag(args))
+			return "+", nil
+		}
+
+		c.panes++
+		return c.capture(global, args, "p", c.panes), nil
+	}
+
+	c.tmux(global, args)
+	return "", nil
+}
+
+func (c *scriptCommander) ExecSilently(cmd *exec.Cmd) error {
+	global, args := splitTmuxArgs(cmd.Args)
+
+	switch args[0] {
+	case "send-keys":
+		if c.sendKeysTimeout > 0 {
+			sleep := strconv.FormatFloat(float64(c.sendKeysTimeout)/1000, 'f', -1, 64)
+			if c.format == FormatTmux {
+				c.lines = append(c.lines, "run-shell "+scriptQuote("sleep "+sleep))
+			} else {
+				c.lines = append(c.lines, "sleep "+sleep)
+			}
+		}
+	case "attach":
+		if c.format == FormatTmux {
+			// A command file is sourced by a running tmux, there is nothing
+			// to attach to
+			return nil
+		}
+
+		target := flagValue(args, "-t")
+		switchClient := append(append([]string{}, global...), "switch-client", "-t", target)
+		c.lines = append(c.lines,
+			`if [ -n "$TMUX" ]; then`,
+			"\t"+scriptJoin(switchClient),
+			"else",
+			"\t"+scriptJoin(cmd.Args),
+			"fi",
+		)
+		return nil
+	}
+
+	c.tmux(global, args)
+	return nil
+}
+
+// shell records a command run by smug through the shell, e.g. before_start.
+func (c *scriptCommander) shell(cmd *exec.Cmd) {
+	command := fmt.Sprintf("cd %s && %s", scriptQuote(cmd.Dir), cmd.Args[len(cmd.Args)-1])
+
+	if c.format == FormatTmux {
+		c.lines = append(c.lines, scriptJoin([]string{"run-shell", command}))
+		return
+	}
+
+	c.lines = append(c.lines, "("+command+")")
+}
+
+func (c *scriptCommander) tmux(global []string, args []string) {
+	if c.format == FormatTmux {
+		c.lines = append(c.lines, scriptJoin(args))
+		return
+	}
+
+	c.lines = append(c.lines, scriptJoin(append(append([]string{}, global...), args...)))
+}
+
+// capture records a command whose output is stored into a shell variable,
+// and returns a reference to the variable.
+func (c *scriptCommander) capture(global []string, args []string, prefix string, n int) string {
+	variable := fmt.Sprintf("smug_%s%d", prefix, n)
+	command := scriptJoin(append(append([]string{}, global...), args...))
+	c.lines = append(c.lines, fmt.Sprintf("%s=$(%s)", variable, command))
+
+	return "${" + variable + "}"
+}
+
+// splitTmuxArgs splits a tmux command line into the global options of the
+// client (socket and config file) and the command with its arguments.
+func splitTmuxArgs(argv []string) ([]string, []string) {
+	i := 1
+	for i+1 < len(argv) && (argv[i] == "-S" || argv[i] == "-L" || argv[i] == "-f") {
+		i += 2
+	}
+
+	return argv[:i], argv[i:]
+}
+
+// withoutPrintFlag drops the flags printing the created session, window or
+// pane, whose output is only used by smug itself.
+func withoutPrintFlag(args []string) []string {
+	var result []string
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case "-Pd":
+			result = append(result, "-d")
+		case "-F":
+			i++
+		default:
+			result = append(result, args[i])
+		}
+	}
+
+	return result
+}
+
+func flagValue(args []string, flag string) string {
+	for i := 0; i+1 < len(args); i++ {
+		if args[i] == flag {
+			return args[i+1]
+		}
+	}
+
+	return ""
+}
+
+// scriptQuote quotes an argument so both the shell and the tmux command
+// parser read it as is, keeping the references to the window and pane
+// variables of the script.
+func scriptQuote(arg string) string {
+	if arg == "" {
+		return "''"
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, loc := range scriptVariable.FindAllStringIndex(arg, -1) {
+		b.WriteString(quoteSegment(arg[last:loc[0]]))
+		b.WriteString(`"` + arg[loc[0]:loc[1]] + `"`)
+		last = loc[1]
+	}
+	b.WriteString(quoteSegment(arg[last:]))
+
+	return b.String()
+}
+
+func quoteSegment(s string) string {
+	if s == "" || strings.IndexFunc(s, needsQuoting) == -1 {
+		return s
+	}
+
+	return "'" + strings.ReplaceAll(s, "'", `'"'"'`) + "'"
+}
+
+func scriptJoin(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		quoted[i] = scriptQuote(a)
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+// Export renders what Smug.Start does for the config as a standalone shell
+// script or as a file to load with `tmux source-file`.
+func Export(config Config, options *Options, tmuxOpts *TmuxOptions, format string) (string, error) {
+	if format != FormatShell && format != FormatTmux {
+		return "", fmt.Errorf("unsupported export format %q, use sh or tmux", format)
+	}
+
+	commander := &scriptCommander{format: format, sendKeysTimeout: config.SendKeysTimeout}
+	config.SendKeysTimeout = 0
+
+	tmux := Tmux{commander, tmuxOpts}
+	smug := Smug{tmux, commander}
+
+	err := smug.Start(&config, options, Context{})
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	if format == FormatTmux {
+		fmt.Fprintf(&b, "# Creates the %s session, load it with `tmux source-file`\n", config.Session)
+	} else {
+		hasSession := tmux.cmd("has-session", "-t", config.Session).Args
+		attach := tmux.cmd("attach", "-d", "-t", config.Session).Args
+		switchClient := tmux.cmd("switch-client", "-t", config.Session).Args
+
+		fmt.Fprintf(&b, "#!/usr/bin/env bash\n# Creates the %s session\nset -e\n\n", config.Session)
+		fmt.Fprintf(&b, "if %s 2>/dev/null; then\n", scriptJoin(hasSession))
+		if !options.Detach {
+			fmt.Fprintf(&b, "\tif [ -n \"$TMUX\" ]; then\n\t\texec %s\n\tfi\n", scriptJoin(switchClient))
+			fmt.Fprintf(&b, "\texec %s\n", scriptJoin(attach))
+		} else {
+			b.WriteString("\texit 0\n")
+		}
+		b.WriteString("fi\n\n")
+	}
+
+	for _, line := range commander.lines {
+		b.WriteString(line + "\n")
+	}
+
+	return b.String(), nil
+}
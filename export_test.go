@@ -0,0 +1,113 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+var exportConfig = Config{
+	Session:         "ses",
+	Root:            "/code/ses",
+	SendKeysTimeout: 100,
+	BeforeStart:     []string{"docker compose up -d"},
+	Windows: []Window{
+		{
+			Name:     "code",
+			Commands: []string{"vim"},
+			Panes: []Pane{
+				{Type: HSplit, Commands: []string{"echo 'it works'"}},
+			},
+		},
+	},
+}
+
+func TestExportShell(t *testing.T) {
+	script, err := Export(exportConfig, &Options{Detach: true}, &TmuxOptions{SocketName: "dev"}, FormatShell)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `#!/usr/bin/env bash
+# Creates the ses session
+set -e
+
+if tmux -L dev has-session -t ses 2>/dev/null; then
+	exit 0
+fi
+
+(cd /code/ses && docker compose up -d)
+tmux -L dev new -d -s ses -n smug_def -c /code/ses
+smug_w1=$(tmux -L dev neww -Pd -t ses: -c /code/ses -F '#{window_id}' -n code)
+sleep 0.1
+tmux -L dev send-keys -t "${smug_w1}" vim Enter
+smug_p1=$(tmux -L dev split-window -Pd -h -t "${smug_w1}" -c /code/ses -F '#{pane_id}')
+tmux -L dev select-layout -t "${smug_w1}" tiled
+sleep 0.1
+tmux -L dev send-keys -t "${smug_w1}"."${smug_p1}" 'echo '"'"'it works'"'"'' Enter
+tmux -L dev select-layout -t "${smug_w1}" even-horizontal
+tmux -L dev kill-window -t ses:smug_def
+tmux -L dev move-window -r -s ses: -t ses:
+`
+
+	if script != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, script)
+	}
+}
+
+func TestExportTmux(t *testing.T) {
+	script, err := Export(exportConfig, &Options{}, &TmuxOptions{}, FormatTmux)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{
+		"# Creates the ses session, load it with `tmux source-file`",
+		"run-shell 'cd /code/ses && docker compose up -d'",
+		"new -d -s ses -n smug_def -c /code/ses",
+		"neww -d -t ses: -c /code/ses -n code",
+		"run-shell 'sleep 0.1'",
+		"send-keys -t ses:code vim Enter",
+		"split-window -d -h -t ses:code -c /code/ses",
+		"select-layout -t ses:code tiled",
+		"run-shell 'sleep 0.1'",
+		`send-keys -t ses:code.+ 'echo '"'"'it works'"'"'' Enter`,
+		"select-layout -t ses:code even-horizontal",
+		"kill-window -t ses:smug_def",
+		"move-window -r -s ses: -t ses:",
+	}
+
+	if script != strings.Join(expected, "\n")+"\n" {
+		t.Errorf("expected\n%s\ngot\n%s", strings.Join(expected, "\n"), script)
+	}
+}
+
+func TestExportUnknownFormat(t *testing.T) {
+	_, err := Export(exportConfig, &Options{}, &TmuxOptions{}, "fish")
+	if err == nil {
+		t.Fatal("expected error for an unknown format")
+	}
+}
+
+func TestExportEnvOrder(t *testing.T) {
+	config := exportConfig
+	config.Env = map[string]string{"FOO": "1", "BAR": "2", "BAZ": "3", "QUX": "4"}
+
+	expected, err := Export(config, &Options{}, &TmuxOptions{}, FormatTmux)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(expected, "setenv -t ses BAR 2\nsetenv -t ses BAZ 3\nsetenv -t ses FOO 1\nsetenv -t ses QUX 4\n") {
+		t.Errorf("expected sorted environment variables, got\n%s", expected)
+	}
+
+	for range 10 {
+		script, err := Export(config, &Options{}, &TmuxOptions{}, FormatTmux)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if script != expected {
+			t.Fatalf("expected the same output on every run, got\n%s\nand\n%s", expected, script)
+		}
+	}
+}
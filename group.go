@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// groupManifestFile describes a group. It lives in the group directory, next
+// to the configs of the members.
+const groupManifestFile = ".group.yml"
+
+// GroupManifest sets the order the members of a group are started in, and
+// the member whose session is attached once all of them run. Members left
+// out of the order are started after the others, in alphabetical order.
+type GroupManifest struct {
+	Order  []string `yaml:"order,omitempty" json:"order,omitempty"`
+	Attach string   `yaml:"attach,omitempty" json:"attach,omitempty"`
+}
+
+// Group is a directory of project configs started and stopped together.
+type Group struct {
+	Name string
+	// Members are the paths of the configs, in start order
+	Members []string
+	// Attach is the path of the config whose session is attached, the last
+	// member unless the manifest names one
+	Attach string
+}
+
+// LoadGroup reads the group in dir and its manifest, if any.
+func LoadGroup(dir string) (Group, error) {
+	group := Group{Name: filepath.Base(dir)}
+
+	configs, err := ListConfigs(dir, false)
+	if err != nil {
+		return group, err
+	}
+
+	members := map[string]string{}
+	var names []string
+	for _, config := range configs {
+		name := strings.TrimSuffix(config, path.Ext(config))
+		members[name] = filepath.Join(dir, config)
+		names = append(names, name)
+	}
+
+	if len(names) == 0 {
+		return group, fmt.Errorf("group %s has no configs", group.Name)
+	}
+
+	manifest := GroupManifest{}
+	data, err := os.ReadFile(filepath.Join(dir, groupManifestFile))
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return group, err
+	}
+	if err := yaml.Unmarshal(data, &manifest); err != nil {
+		return group, fmt.Errorf("group %s: %w", group.Name, err)
+	}
+
+	for _, name := range manifest.Order {
+		if _, ok := members[name]; !ok {
+			return group, fmt.Errorf("group %s: unknown member %q in order", group.Name, name)
+		}
+		if !slices.Contains(group.Members, members[name]) {
+			group.Members = append(group.Members, members[name])
+		}
+	}
+	for _, name := range names {
+		if !slices.Contains(group.Members, members[name]) {
+			group.Members = append(group.Members, members[name])
+		}
+	}
+
+	group.Attach = group.Members[len(group.Members)-1]
+	if manifest.Attach != "" {
+		attach, ok := members[manifest.Attach]
+		if !ok {
+			return group, fmt.Errorf("group %s: unknown member %q to attach", group.Name, manifest.Attach)
+		}
+		group.Attach = attach
+	}
+
+	return group, nil
+}
+
+// FindGroup returns the group from the first directory of dirs that has it.
+func FindGroup(dirs []string, name string) (Group, error) {
+	for _, dir := range dirs {
+		groupDir := filepath.Join(dir, name)
+		if isDir, _ := IsDirectory(groupDir); isDir {
+			return LoadGroup(groupDir)
+		}
+	}
+
+	return Group{}, ConfigNotFoundError{Project: name}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeGroup(t *testing.T, dir string, manifest string, members ...string) {
+	t.Helper()
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, member := range members {
+		if err := os.WriteFile(filepath.Join(dir, member+".yml"), []byte("session: "+member+"\n"), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if manifest != "" {
+		if err := os.WriteFile(filepath.Join(dir, groupManifestFile), []byte(manifest), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+var loadGroupTestTable = map[string]struct {
+	manifest string
+	members  []string
+	attach   string
+	err      bool
+}{
+	"without manifest": {
+		members: []string{"api", "db", "web"},
+		attach:  "web",
+	},
+	"with order": {
+		manifest: "order: [db, web]\n",
+		members:  []string{"db", "web", "api"},
+		attach:   "api",
+	},
+	"with attach": {
+		manifest: "order: [db]\nattach: web\n",
+		members:  []string{"db", "api", "web"},
+		attach:   "web",
+	},
+	"unknown member in order": {
+		manifest: "order: [cache]\n",
+		err:      true,
+	},
+	"unknown member to attach": {
+		manifest: "attach: cache\n",
+		err:      true,
+	},
+}
+
+func TestLoadGroup(t *testing.T) {
+	for testDescription, params := range loadGroupTestTable {
+		t.Run(testDescription, func(t *testing.T) {
+			dir := filepath.Join(t.TempDir(), "backend")
+			writeGroup(t, dir, params.manifest, "web", "api", "db")
+
+			group, err := LoadGroup(dir)
+			if params.err {
+				if err == nil {
+					t.Errorf("expected an error, got %v", group)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var members []string
+			for _, member := range params.members {
+				members = append(members, filepath.Join(dir, member+".yml"))
+			}
+
+			if group.Name != "backend" || !reflect.DeepEqual(members, group.Members) {
+				t.Errorf("expected members %v, got %v", members, group.Members)
+			}
+
+			if attach := filepath.Join(dir, params.attach+".yml"); group.Attach != attach {
+				t.Errorf("expected to attach %s, got %s", attach, group.Attach)
+			}
+		})
+	}
+}
+
+func TestFindGroupConfigs(t *testing.T) {
+	dir := t.TempDir()
+	writeGroup(t, filepath.Join(dir, "backend"), "order: [db]\n", "api", "db")
+
+	configs, err := FindConfigs([]string{dir}, "backend")
+	expected := []string{dir + "/backend/db.yml", dir + "/backend/api.yml"}
+	if err != nil || !reflect.DeepEqual(expected, configs) {
+		t.Errorf("expected %v, got %v (%v)", expected, configs, err)
+	}
+
+	configs, err = FindConfigs([]string{dir}, "backend/api")
+	expected = []string{dir + "/backend/api.yml"}
+	if err != nil || !reflect.DeepEqual(expected, configs) {
+		t.Errorf("expected %v, got %v (%v)", expected, configs, err)
+	}
+
+	if _, err := FindConfigs([]string{dir}, "backend/cache"); err == nil {
+		t.Errorf("expected an error for an unknown member")
+	}
+
+	group, err := FindGroup([]string{dir}, "backend")
+	if err != nil || group.Attach != filepath.Join(dir, "backend", "api.yml") {
+		t.Errorf("unexpected group %v (%v)", group, err)
+	}
+}
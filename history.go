@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// maxHistoryEntries bounds the number of projects the history remembers.
+const maxHistoryEntries = 100
+
+// HistoryFile returns the file recording the projects started with smug.
+func HistoryFile() string {
+	return filepath.Join(StateDir(), "history.json")
+}
+
+// HistoryEntry records how often and when a project was last started or
+// switched to.
+type HistoryEntry struct {
+	Project  string    `json:"project"`
+	Session  string    `json:"session"`
+	Visits   int       `json:"visits"`
+	LastUsed time.Time `json:"last_used"`
+}
+
+// frecency ranks the entry by how often and how recently the project was
+// used, like z and zoxide do.
+func (e HistoryEntry) frecency(now time.Time) float64 {
+	age := now.Sub(e.LastUsed)
+
+	switch {
+	case age < time.Hour:
+		return float64(e.Visits) * 4
+	case age < 24*time.Hour:
+		return float64(e.Visits) * 2
+	case age < 7*24*time.Hour:
+		return float64(e.Visits) / 2
+	default:
+		return float64(e.Visits) / 4
+	}
+}
+
+// History is the list of the used projects, the most recently used first.
+type History []HistoryEntry
+
+// ReadHistory reads the history file at path. A missing file is an empty
+// history.
+func ReadHistory(path string) (History, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return History{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	history := History{}
+	if err := json.Unmarshal(data, &history); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// RecordVisit records that the project, whose session is session, was used
+// at now.
+func RecordVisit(path string, project string, session string, now time.Time) error {
+	history, err := ReadHistory(path)
+	if err != nil {
+		// A corrupted history is started over rather than blocking smug
+		history = History{}
+	}
+
+	entry := HistoryEntry{Project: project}
+	if i := slices.IndexFunc(history, func(e HistoryEntry) bool { return e.Project == project }); i != -1 {
+		entry = history[i]
+		history = slices.Delete(history, i, i+1)
+	}
+
+	entry.Session = session
+	entry.Visits++
+	entry.LastUsed = now
+
+	history = slices.Insert(history, 0, entry)
+	if len(history) > maxHistoryEntries {
+		history = history[:maxHistoryEntries]
+	}
+
+	data, err := json.MarshalIndent(history, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o750); err != nil {
+		return err
+	}
+
+	// Written to a temporary file first, so concurrent smug runs never read
+	// a partial history
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".history-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Frecent returns the projects ranked by frecency at now, best first.
+func (h History) Frecent(now time.Time) []string {
+	ranked := slices.Clone(h)
+	slices.SortStableFunc(ranked, func(a, b HistoryEntry) int {
+		fa, fb := a.frecency(now), b.frecency(now)
+		switch {
+		case fa > fb:
+			return -1
+		case fa < fb:
+			return 1
+		}
+		return 0
+	})
+
+	projects := make([]string, len(ranked))
+	for i, e := range ranked {
+		projects[i] = e.Project
+	}
+
+	return projects
+}
+
+// Last returns the most recently used project whose session is not the
+// current one, so smug last goes back and forth between two projects.
+func (h History) Last(currentSession string) (HistoryEntry, bool) {
+	for _, e := range h {
+		if e.Session != currentSession || currentSession == "" {
+			return e, true
+		}
+	}
+
+	return HistoryEntry{}, false
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestRecordVisit(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "state", "history.json")
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+
+	for _, visit := range []struct {
+		project string
+		session string
+		at      time.Duration
+	}{
+		{"blog", "blog", -72 * time.Hour},
+		{"api", "api", -48 * time.Hour},
+		{"blog", "blog", -30 * time.Minute},
+		{"backend", "worker", -10 * time.Minute},
+	} {
+		if err := RecordVisit(path, visit.project, visit.session, now.Add(visit.at)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history, err := ReadHistory(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := History{
+		{Project: "backend", Session: "worker", Visits: 1, LastUsed: now.Add(-10 * time.Minute)},
+		{Project: "blog", Session: "blog", Visits: 2, LastUsed: now.Add(-30 * time.Minute)},
+		{Project: "api", Session: "api", Visits: 1, LastUsed: now.Add(-48 * time.Hour)},
+	}
+	if !reflect.DeepEqual(expected, history) {
+		t.Errorf("expected %v, got %v", expected, history)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if err != nil || len(entries) != 1 {
+		t.Errorf("expected only the history file, got %v (%v)", entries, err)
+	}
+}
+
+func TestReadMissingHistory(t *testing.T) {
+	history, err := ReadHistory(filepath.Join(t.TempDir(), "history.json"))
+	if err != nil || len(history) != 0 {
+		t.Errorf("expected an empty history, got %v (%v)", history, err)
+	}
+}
+
+func TestHistoryFrecent(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	history := History{
+		{Project: "docs", Visits: 1, LastUsed: now.Add(-time.Minute)},
+		{Project: "blog", Visits: 3, LastUsed: now.Add(-2 * time.Hour)},
+		{Project: "api", Visits: 20, LastUsed: now.Add(-30 * 24 * time.Hour)},
+		{Project: "web", Visits: 1, LastUsed: now.Add(-3 * time.Hour)},
+	}
+
+	expected := []string{"blog", "api", "docs", "web"}
+	if projects := history.Frecent(now); !reflect.DeepEqual(expected, projects) {
+		t.Errorf("expected %v, got %v", expected, projects)
+	}
+}
+
+func TestHistoryLast(t *testing.T) {
+	history := History{
+		{Project: "blog", Session: "blog"},
+		{Project: "backend", Session: "worker"},
+	}
+
+	if entry, ok := history.Last("blog"); !ok || entry.Project != "backend" {
+		t.Errorf("expected backend, got %v", entry)
+	}
+
+	if entry, ok := history.Last("other"); !ok || entry.Project != "blog" {
+		t.Errorf("expected blog, got %v", entry)
+	}
+
+	if entry, ok := history.Last(""); !ok || entry.Project != "blog" {
+		t.Errorf("expected blog outside tmux, got %v", entry)
+	}
+
+	if _, ok := history[:1].Last("blog"); ok {
+		t.Errorf("expected no other project")
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Importer converts a configuration of another tool into smug configs. It
+// also returns warnings about the features that couldn't be mapped.
+type Importer func(path string, options *Options) ([]Config, []string, error)
+
+var importers = map[string]Importer{
+	"tmuxinator": ImportTmuxinator,
+	"tmuxp":      ImportTmuxp,
+	"resurrect":  ImportResurrect,
+	"procfile":   ImportProcfile,
+	"compose":    ImportCompose,
+	"npm":        ImportNpm,
+}
+
+type UnknownImporterError struct {
+	Name string
+}
+
+func (e UnknownImporterError) Error() string {
+	names := make([]string, 0, len(importers))
+	for name := range importers {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	return fmt.Sprintf("cannot import from %q, supported formats: %s", e.Name, strings.Join(names, ", "))
+}
+
+// Import converts the file at path with the importer registered as from.
+func Import(from string, path string, options *Options) ([]Config, []string, error) {
+	importer, ok := importers[from]
+	if !ok {
+		return nil, nil, UnknownImporterError{Name: from}
+	}
+
+	return importer(path, options)
+}
+
+// WriteConfig saves the config as <session>.yml into dir, refusing to
+// overwrite an existing config.
+func WriteConfig(dir string, config Config) (string, error) {
+	path := filepath.Join(dir, sessionFileName(config.Session)+".yml")
+	if _, err := os.Stat(path); err == nil {
+		return "", fmt.Errorf("config %s already exists", path)
+	}
+
+	d, err := yaml.Marshal(&config)
+	if err != nil {
+		return "", err
+	}
+
+	return path, os.WriteFile(path, d, 0o644)
+}
+
+// readImportFile decodes a YAML or JSON file into a generic map.
+func readImportFile(path string) (map[string]any, error) {
+	if path == "" {
+		return nil, errors.New("import requires a file to import")
+	}
+
+	f, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	data := map[string]any{}
+	if err := yaml.Unmarshal(f, &data); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	return data, nil
+}
+
+// sessionNameFromPath is used when the imported file doesn't name its session.
+func sessionNameFromPath(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
+
+// importString converts a scalar value into a string.
+func importString(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// importCommands converts a command or a list of commands into a list of
+// commands. Commands given as maps use the cmd key, as in tmuxp.
+func importCommands(v any) []string {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []any:
+		var commands []string
+		for _, c := range v {
+			commands = append(commands, importCommands(c)...)
+		}
+		return commands
+	case map[string]any:
+		return importCommands(v["cmd"])
+	case map[any]any:
+		return importCommands(v["cmd"])
+	default:
+		if c := importString(v); c != "" {
+			return []string{c}
+		}
+		return nil
+	}
+}
+
+// importMap converts a YAML mapping into a map with string keys. Mappings
+// with non-string keys, e.g. numbers as window names, are decoded with any
+// keys.
+func importMap(v any) (map[string]any, bool) {
+	switch v := v.(type) {
+	case map[string]any:
+		return v, true
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for key, value := range v {
+			m[importString(key)] = value
+		}
+		return m, true
+	}
+
+	return nil, false
+}
+
+// unmappedKeys returns a warning for every key of data that isn't known.
+func unmappedKeys(prefix string, data map[string]any, known []string) []string {
+	var warnings []string
+	for key := range data {
+		if !slices.Contains(known, key) {
+			warnings = append(warnings, fmt.Sprintf("%s%s is not supported", prefix, key))
+		}
+	}
+	slices.Sort(warnings)
+
+	return warnings
+}
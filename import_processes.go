@@ -0,0 +1,306 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// process is a named command of a Procfile, a compose file or package.json
+type process struct {
+	name    string
+	command string
+}
+
+var composeFiles = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// importPath returns path, or the first of the default files that exists
+// in the current directory.
+func importPath(path string, defaults ...string) (string, error) {
+	if path != "" {
+		return path, nil
+	}
+
+	for _, file := range defaults {
+		if _, err := os.Stat(file); err == nil {
+			return file, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s not found in the current directory", strings.Join(defaults, ", "))
+}
+
+// processesConfig builds a config rooted at the directory of the file, with
+// a window per process or, with --panes, a single window with a pane per
+// process. Only the processes named by --windows are imported, if any.
+func processesConfig(path string, session string, processes []process, options *Options) (Config, []string, error) {
+	var warnings []string
+
+	root, err := filepath.Abs(filepath.Dir(path))
+	if err != nil {
+		return Config{}, nil, err
+	}
+
+	if session == "" {
+		session = filepath.Base(root)
+	}
+
+	if len(options.Windows) > 0 {
+		var selected []process
+		for _, name := range options.Windows {
+			i := slices.IndexFunc(processes, func(p process) bool { return p.name == name })
+			if i == -1 {
+				warnings = append(warnings, fmt.Sprintf("%s is not defined in %s", name, path))
+				continue
+			}
+			selected = append(selected, processes[i])
+		}
+		processes = selected
+	}
+
+	if len(processes) == 0 {
+		return Config{}, warnings, fmt.Errorf("nothing to import from %s", path)
+	}
+
+	config := Config{
+		Session: session,
+		Root:    root,
+	}
+
+	if !options.Panes {
+		for _, p := range processes {
+			config.Windows = append(config.Windows, Window{
+				Name:     p.name,
+				Commands: []string{p.command},
+			})
+		}
+
+		return config, warnings, nil
+	}
+
+	window := Window{
+		Name:     session,
+		Layout:   Tiled,
+		Commands: []string{processes[0].command},
+	}
+	for _, p := range processes[1:] {
+		window.Panes = append(window.Panes, Pane{Commands: []string{p.command}})
+	}
+	config.Windows = []Window{window}
+
+	return config, warnings, nil
+}
+
+var procfileLine = regexp.MustCompile(`^([A-Za-z0-9_-]+):\s*(.+)$`)
+
+// ImportProcfile converts the processes of a Procfile.
+func ImportProcfile(path string, options *Options) ([]Config, []string, error) {
+	path, err := importPath(path, "Procfile")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var processes []process
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		match := procfileLine.FindStringSubmatch(line)
+		if match == nil {
+			return nil, nil, fmt.Errorf("%s: malformed line %q", path, line)
+		}
+		processes = append(processes, process{name: match[1], command: match[2]})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	config, warnings, err := processesConfig(path, "", processes, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return []Config{config}, warnings, nil
+}
+
+// ImportCompose converts the services of a compose file. The services are
+// started in the background before the session is created, and every window
+// follows the logs of a service.
+func ImportCompose(path string, options *Options) ([]Config, []string, error) {
+	path, err := importPath(path, composeFiles...)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root, err := orderedMapping(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	services := mappingValue(root, "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("%s has no services", path)
+	}
+
+	compose := "docker compose -f " + shellQuote([]string{filepath.Base(path)})
+
+	var processes []process
+	for i := 0; i < len(services.Content); i += 2 {
+		name := services.Content[i].Value
+		processes = append(processes, process{
+			name:    name,
+			command: compose + " logs -f " + shellQuote([]string{name}),
+		})
+	}
+
+	config, warnings, err := processesConfig(path, mappingScalar(root, "name"), processes, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	up := compose + " up -d"
+	for _, name := range options.Windows {
+		if slices.ContainsFunc(processes, func(p process) bool { return p.name == name }) {
+			up += " " + shellQuote([]string{name})
+		}
+	}
+	config.BeforeStart = []string{up}
+	config.Stop = []string{compose + " stop"}
+
+	return []Config{config}, warnings, nil
+}
+
+// ImportNpm converts the scripts of a package.json. Without --windows, all
+// scripts except the pre and post hooks of other scripts are imported.
+func ImportNpm(path string, options *Options) ([]Config, []string, error) {
+	path, err := importPath(path, "package.json")
+	if err != nil {
+		return nil, nil, err
+	}
+
+	root, err := orderedMapping(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	scripts := mappingValue(root, "scripts")
+	if scripts == nil || scripts.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("%s has no scripts", path)
+	}
+
+	var names []string
+	for i := 0; i < len(scripts.Content); i += 2 {
+		names = append(names, scripts.Content[i].Value)
+	}
+
+	run := npmRunner(filepath.Dir(path))
+
+	var processes []process
+	for _, name := range names {
+		if len(options.Windows) == 0 && isLifecycleHook(name, names) {
+			continue
+		}
+		processes = append(processes, process{name: name, command: run + " " + shellQuote([]string{name})})
+	}
+
+	// Scoped packages are named @scope/name
+	session := mappingScalar(root, "name")
+	if i := strings.LastIndex(session, "/"); i != -1 {
+		session = session[i+1:]
+	}
+
+	config, warnings, err := processesConfig(path, session, processes, options)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return []Config{config}, warnings, nil
+}
+
+// isLifecycleHook reports whether the script is run by npm before or after
+// another script of names, as prex and postx are around x.
+func isLifecycleHook(name string, names []string) bool {
+	for _, prefix := range []string{"pre", "post"} {
+		script, ok := strings.CutPrefix(name, prefix)
+		if ok && script != "" && slices.Contains(names, script) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// npmRunner returns the command running a script with the package manager
+// whose lockfile is in dir.
+func npmRunner(dir string) string {
+	lockfiles := []struct {
+		file   string
+		runner string
+	}{
+		{"pnpm-lock.yaml", "pnpm run"},
+		{"yarn.lock", "yarn run"},
+		{"bun.lockb", "bun run"},
+		{"bun.lock", "bun run"},
+	}
+
+	for _, l := range lockfiles {
+		if _, err := os.Stat(filepath.Join(dir, l.file)); err == nil {
+			return l.runner
+		}
+	}
+
+	return "npm run"
+}
+
+// orderedMapping decodes a YAML or JSON file keeping the order of its keys.
+func orderedMapping(path string) (*yaml.Node, error) {
+	f, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(f, &doc); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+
+	if len(doc.Content) == 0 || doc.Content[0].Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("parse %s: not a mapping", path)
+	}
+
+	return doc.Content[0], nil
+}
+
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
+
+func mappingScalar(node *yaml.Node, key string) string {
+	if value := mappingValue(node, key); value != nil && value.Kind == yaml.ScalarNode {
+		return value.Value
+	}
+
+	return ""
+}
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// resurrectLastFiles returns the locations of the last tmux-resurrect save,
+// from the most to the least preferred.
+func resurrectLastFiles() []string {
+	dataDir := os.Getenv("XDG_DATA_HOME")
+	if dataDir == "" {
+		dataDir = ExpandPath("~/.local/share")
+	}
+
+	return []string{
+		filepath.Join(dataDir, "tmux/resurrect/last"),
+		ExpandPath("~/.tmux/resurrect/last"),
+	}
+}
+
+type resurrectPane struct {
+	index   int
+	root    string
+	command string
+}
+
+type resurrectWindow struct {
+	index    int
+	name     string
+	layout   string
+	selected bool
+	panes    []resurrectPane
+}
+
+// ImportResurrect converts a tmux-resurrect save file into one config per
+// saved session. Without a path it reads the last save.
+func ImportResurrect(path string, options *Options) ([]Config, []string, error) {
+	if path == "" {
+		for _, last := range resurrectLastFiles() {
+			if _, err := os.Stat(last); err == nil {
+				path = last
+				break
+			}
+		}
+
+		if path == "" {
+			return nil, nil, errors.New("no tmux-resurrect save found")
+		}
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer f.Close()
+
+	var warnings []string
+	var sessions []string
+	windows := map[string]map[int]*resurrectWindow{}
+
+	window := func(session string, index int) *resurrectWindow {
+		if _, ok := windows[session]; !ok {
+			windows[session] = map[int]*resurrectWindow{}
+			sessions = append(sessions, session)
+		}
+		if _, ok := windows[session][index]; !ok {
+			windows[session][index] = &resurrectWindow{index: index}
+		}
+		return windows[session][index]
+	}
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		fields := strings.Split(scanner.Text(), "\t")
+
+		switch fields[0] {
+		case "pane":
+			// Saves made before pane titles were recorded have one field less
+			if len(fields) == 10 {
+				fields = slices.Insert(fields, 6, "")
+			}
+			if len(fields) < 11 {
+				return nil, nil, fmt.Errorf("%s:%d: malformed pane line", path, lineNumber)
+			}
+
+			windowIndex, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s:%d: malformed window index", path, lineNumber)
+			}
+			paneIndex, err := strconv.Atoi(fields[5])
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s:%d: malformed pane index", path, lineNumber)
+			}
+
+			w := window(fields[1], windowIndex)
+			w.panes = append(w.panes, resurrectPane{
+				index:   paneIndex,
+				root:    strings.ReplaceAll(strings.TrimPrefix(fields[7], ":"), `\ `, " "),
+				command: strings.TrimPrefix(fields[10], ":"),
+			})
+		case "window":
+			if len(fields) < 7 {
+				return nil, nil, fmt.Errorf("%s:%d: malformed window line", path, lineNumber)
+			}
+
+			windowIndex, err := strconv.Atoi(fields[2])
+			if err != nil {
+				return nil, nil, fmt.Errorf("%s:%d: malformed window index", path, lineNumber)
+			}
+
+			w := window(fields[1], windowIndex)
+			w.name = strings.TrimPrefix(fields[3], ":")
+			w.selected = fields[4] == "1"
+			w.layout = fields[6]
+		case "state", "":
+			// The attached sessions of the clients, nothing to map
+		default:
+			warnings = append(warnings, fmt.Sprintf("%s lines are not supported", fields[0]))
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, nil, err
+	}
+
+	var configs []Config
+	for _, session := range sessions {
+		configs = append(configs, resurrectConfig(session, windows[session]))
+	}
+
+	return configs, slices.Compact(warnings), nil
+}
+
+func resurrectConfig(session string, windows map[int]*resurrectWindow) Config {
+	config := Config{Session: session}
+
+	indexes := make([]int, 0, len(windows))
+	for index := range windows {
+		indexes = append(indexes, index)
+	}
+	slices.Sort(indexes)
+
+	for _, index := range indexes {
+		w := windows[index]
+		slices.SortFunc(w.panes, func(a, b resurrectPane) int {
+			return a.index - b.index
+		})
+
+		window := Window{
+			Selected: w.selected,
+			Name:     w.name,
+			Layout:   w.layout,
+		}
+
+		for i, p := range w.panes {
+			var commands []string
+			if p.command != "" {
+				commands = []string{p.command}
+			}
+
+			if i == 0 {
+				window.Root = p.root
+				window.Commands = commands
+				continue
+			}
+			window.Panes = append(window.Panes, Pane{Root: p.root, Commands: commands})
+		}
+
+		config.Windows = append(config.Windows, window)
+	}
+
+	relativizeRoots(&config)
+
+	return config
+}
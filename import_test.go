@@ -0,0 +1,340 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeImportFile(t *testing.T, name, contents string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(contents), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestImportTmuxinator(t *testing.T) {
+	path := writeImportFile(t, "blog.yml", `
+name: blog
+root: ~/code/blog
+on_project_first_start: docker compose up -d
+on_project_stop: docker compose stop
+pre_window: nvm use
+startup_window: logs
+tmux_options: -f ~/.tmux.blog.conf -2
+synchronize: true
+windows:
+  - editor:
+      layout: main-vertical
+      panes:
+        - vim
+        - guard
+  - server: bundle exec rails s
+  - logs:
+      root: log
+      synchronize: after
+      panes:
+        - tail:
+            - cd production
+            - tail -f production.log
+  - 1:
+`)
+
+	configs, warnings, err := Import("tmuxinator", path, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Config{
+		Session:     "blog",
+		Root:        "~/code/blog",
+		BeforeStart: []string{"docker compose up -d"},
+		Stop:        []string{"docker compose stop"},
+		TmuxOptions: TmuxOptions{ConfigFile: "~/.tmux.blog.conf"},
+		Windows: []Window{
+			{
+				Name:     "editor",
+				Layout:   "main-vertical",
+				Commands: []string{"nvm use", "vim"},
+				Panes:    []Pane{{Commands: []string{"nvm use", "guard"}}},
+			},
+			{
+				Name:     "server",
+				Commands: []string{"nvm use", "bundle exec rails s"},
+			},
+			{
+				Selected: true,
+				Name:     "logs",
+				Root:     "log",
+				Commands: []string{"nvm use", "cd production", "tail -f production.log"},
+			},
+			{
+				Name:     "1",
+				Commands: []string{"nvm use"},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual([]Config{expected}, configs) {
+		t.Errorf("expected %v, got %v", expected, configs)
+	}
+
+	expectedWarnings := []string{
+		"synchronize is not supported",
+		"tmux_options -2 is not supported",
+		"windows.logs.synchronize is not supported",
+	}
+	if !reflect.DeepEqual(expectedWarnings, warnings) {
+		t.Errorf("expected warnings %q, got %q", expectedWarnings, warnings)
+	}
+}
+
+func TestImportTmuxp(t *testing.T) {
+	path := writeImportFile(t, "api.json", `{
+  "session_name": "api",
+  "start_directory": "~/code/api",
+  "before_script": "./bootstrap.sh",
+  "shell_command_before": ["source .env"],
+  "environment": {"PORT": 8080},
+  "options": {"mouse": "on"},
+  "windows": [
+    {
+      "window_name": "server",
+      "layout": "tiled",
+      "focus": true,
+      "panes": [
+        {"shell_command": [{"cmd": "make run"}]},
+        "blank",
+        {"shell_command": "make test", "start_directory": "tests", "focus": true}
+      ]
+    },
+    {"window_name": "shell"}
+  ]
+}`)
+
+	configs, warnings, err := Import("tmuxp", path, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Config{
+		Session:     "api",
+		Root:        "~/code/api",
+		BeforeStart: []string{"./bootstrap.sh"},
+		Env:         map[string]string{"PORT": "8080"},
+		Windows: []Window{
+			{
+				Selected: true,
+				Name:     "server",
+				Layout:   "tiled",
+				Commands: []string{"source .env", "make run"},
+				Panes: []Pane{
+					{Commands: []string{"source .env"}},
+					{Root: "tests", Commands: []string{"source .env", "make test"}},
+				},
+			},
+			{
+				Name:     "shell",
+				Commands: []string{"source .env"},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual([]Config{expected}, configs) {
+		t.Errorf("expected %v, got %v", expected, configs)
+	}
+
+	expectedWarnings := []string{
+		"options is not supported",
+		"windows.server.panes.focus is not supported",
+	}
+	if !reflect.DeepEqual(expectedWarnings, warnings) {
+		t.Errorf("expected warnings %q, got %q", expectedWarnings, warnings)
+	}
+}
+
+func TestImportUnknownFormat(t *testing.T) {
+	_, _, err := Import("screen", "file", &Options{})
+	if err == nil {
+		t.Fatal("expected error for an unknown format")
+	}
+}
+
+func TestImportResurrect(t *testing.T) {
+	lines := []string{
+		"pane\tblog\t1\t1\t:*\t0\ttitle\t:/home/user/blog\t1\tvim\t:vim README.md",
+		"pane\tblog\t1\t1\t:*\t1\ttitle\t:/home/user/blog/my\\ notes\t0\tbash\t:",
+		"pane\tblog\t2\t0\t:-\t0\ttitle\t:/home/user/blog/log\t1\ttail\t:tail -f app.log",
+		"pane\tscratch\t0\t1\t:*\t0\t:/tmp\t1\tbash\t:",
+		"window\tblog\t1\t:code\t1\t:*\tb25f,80x24,0,0{40x24,0,0,1,39x24,41,0,2}\toff",
+		"window\tblog\t2\t:logs\t0\t:-\tb260,80x24,0,0,3\ton",
+		"window\tscratch\t0\t:bash\t1\t:*\tb25e,80x24,0,0,0",
+		"grouped_session\tblog-2\tblog\t:1\t:2",
+		"state\tblog\tscratch",
+	}
+	path := writeImportFile(t, "last", strings.Join(lines, "\n")+"\n")
+
+	configs, warnings, err := Import("resurrect", path, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Config{
+		{
+			Session: "blog",
+			Root:    "/home/user/blog",
+			Windows: []Window{
+				{
+					Selected: true,
+					Name:     "code",
+					Layout:   "b25f,80x24,0,0{40x24,0,0,1,39x24,41,0,2}",
+					Commands: []string{"vim README.md"},
+					Panes:    []Pane{{Root: "my notes"}},
+				},
+				{
+					Name:     "logs",
+					Root:     "log",
+					Layout:   "b260,80x24,0,0,3",
+					Commands: []string{"tail -f app.log"},
+				},
+			},
+		},
+		{
+			Session: "scratch",
+			Root:    "/tmp",
+			Windows: []Window{
+				{
+					Selected: true,
+					Name:     "bash",
+					Layout:   "b25e,80x24,0,0,0",
+				},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(expected, configs) {
+		t.Errorf("expected %v, got %v", expected, configs)
+	}
+
+	if !reflect.DeepEqual([]string{"grouped_session lines are not supported"}, warnings) {
+		t.Errorf("unexpected warnings %q", warnings)
+	}
+}
+
+func TestImportProcfile(t *testing.T) {
+	path := writeImportFile(t, "Procfile", `
+# processes
+web: bundle exec rails s -p $PORT
+worker: bundle exec sidekiq
+`)
+
+	configs, _, err := Import("procfile", path, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := filepath.Dir(path)
+	expected := []Config{
+		{
+			Session: filepath.Base(dir),
+			Root:    dir,
+			Windows: []Window{
+				{Name: "web", Commands: []string{"bundle exec rails s -p $PORT"}},
+				{Name: "worker", Commands: []string{"bundle exec sidekiq"}},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(expected, configs) {
+		t.Errorf("expected %v, got %v", expected, configs)
+	}
+}
+
+func TestImportCompose(t *testing.T) {
+	path := writeImportFile(t, "compose.yaml", `
+name: shop
+services:
+  db:
+    image: postgres
+  api:
+    build: .
+  web:
+    build: ./web
+`)
+
+	configs, warnings, err := Import("compose", path, &Options{Windows: []string{"web", "api", "cache"}, Panes: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Config{
+		{
+			Session:     "shop",
+			Root:        filepath.Dir(path),
+			BeforeStart: []string{"docker compose -f compose.yaml up -d web api"},
+			Stop:        []string{"docker compose -f compose.yaml stop"},
+			Windows: []Window{
+				{
+					Name:     "shop",
+					Layout:   Tiled,
+					Commands: []string{"docker compose -f compose.yaml logs -f web"},
+					Panes:    []Pane{{Commands: []string{"docker compose -f compose.yaml logs -f api"}}},
+				},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(expected, configs) {
+		t.Errorf("expected %v, got %v", expected, configs)
+	}
+
+	if !reflect.DeepEqual([]string{"cache is not defined in " + path}, warnings) {
+		t.Errorf("unexpected warnings %q", warnings)
+	}
+}
+
+func TestImportNpm(t *testing.T) {
+	path := writeImportFile(t, "package.json", `{
+  "name": "@acme/storefront",
+  "scripts": {
+    "predev": "node check.js",
+    "dev": "vite",
+    "test": "vitest --watch",
+    "prettier": "prettier -w .",
+    "lint": "eslint .",
+    "postlint": "echo done",
+    "build": "vite build",
+    "prepostbuild": "echo first"
+  }
+}`)
+	if err := os.WriteFile(filepath.Join(filepath.Dir(path), "pnpm-lock.yaml"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	configs, _, err := Import("npm", path, &Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []Config{
+		{
+			Session: "storefront",
+			Root:    filepath.Dir(path),
+			Windows: []Window{
+				{Name: "dev", Commands: []string{"pnpm run dev"}},
+				{Name: "test", Commands: []string{"pnpm run test"}},
+				{Name: "prettier", Commands: []string{"pnpm run prettier"}},
+				{Name: "lint", Commands: []string{"pnpm run lint"}},
+				{Name: "build", Commands: []string{"pnpm run build"}},
+				{Name: "prepostbuild", Commands: []string{"pnpm run prepostbuild"}},
+			},
+		},
+	}
+
+	if !reflect.DeepEqual(expected, configs) {
+		t.Errorf("expected %v, got %v", expected, configs)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// ImportTmuxinator converts a tmuxinator project file.
+func ImportTmuxinator(path string, options *Options) ([]Config, []string, error) {
+	if f, err := os.ReadFile(path); err == nil && strings.Contains(string(f), "<%") {
+		return nil, nil, fmt.Errorf("%s uses ERB, render it with `tmuxinator debug` first", path)
+	}
+
+	data, err := readImportFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	warnings := unmappedKeys("", data, []string{
+		"name", "project_name", "root", "project_root", "pre", "on_project_first_start",
+		"on_project_start", "on_project_stop", "on_project_exit", "pre_window",
+		"startup_window", "tmux_options", "socket_name", "attach", "windows", "tabs",
+	})
+
+	config := Config{
+		Session: importString(data["name"]),
+		Root:    importString(data["root"]),
+	}
+	if config.Session == "" {
+		config.Session = importString(data["project_name"])
+	}
+	if config.Session == "" {
+		config.Session = sessionNameFromPath(path)
+	}
+	if config.Root == "" {
+		config.Root = importString(data["project_root"])
+	}
+
+	config.BeforeStart = append(config.BeforeStart, importCommands(data["pre"])...)
+	config.BeforeStart = append(config.BeforeStart, importCommands(data["on_project_first_start"])...)
+	if start := importCommands(data["on_project_start"]); len(start) > 0 {
+		config.BeforeStart = append(config.BeforeStart, start...)
+		warnings = append(warnings, "on_project_start is imported as before_start, it runs only when the session is created")
+	}
+	config.Stop = importCommands(data["on_project_stop"])
+	if exit := importCommands(data["on_project_exit"]); len(exit) > 0 {
+		config.DetachHook = strings.Join(exit, "; ")
+	}
+
+	if attach, ok := data["attach"].(bool); ok && !attach {
+		warnings = append(warnings, "attach: false is not supported, use smug start --detach")
+	}
+
+	config.SocketName = importString(data["socket_name"])
+	warnings = append(warnings, tmuxinatorTmuxOptions(&config, importString(data["tmux_options"]))...)
+
+	preWindow := importCommands(data["pre_window"])
+
+	windows, _ := data["windows"].([]any)
+	if windows == nil {
+		windows, _ = data["tabs"].([]any)
+	}
+
+	for _, w := range windows {
+		window, windowWarnings := tmuxinatorWindow(w, preWindow)
+		warnings = append(warnings, windowWarnings...)
+		config.Windows = append(config.Windows, window)
+	}
+
+	if startup := importString(data["startup_window"]); startup != "" {
+		selectStartupWindow(config.Windows, startup)
+	}
+
+	return []Config{config}, warnings, nil
+}
+
+// tmuxinatorWindow converts a window, given as a map with the window name as
+// its only key.
+func tmuxinatorWindow(w any, preWindow []string) (Window, []string) {
+	var warnings []string
+	window := Window{}
+
+	entry, _ := importMap(w)
+	for name, value := range entry {
+		window.Name = name
+
+		options, ok := importMap(value)
+		if !ok {
+			// A command or a list of commands
+			window.Commands = append(window.Commands, preWindow...)
+			window.Commands = append(window.Commands, importCommands(value)...)
+			continue
+		}
+
+		prefix := "windows." + name + "."
+		warnings = append(warnings, unmappedKeys(prefix, options, []string{"layout", "root", "panes", "pre"})...)
+
+		window.Layout = importString(options["layout"])
+		window.Root = importString(options["root"])
+		pre := append(append([]string{}, preWindow...), importCommands(options["pre"])...)
+
+		panes, _ := options["panes"].([]any)
+		if len(panes) == 0 {
+			window.Commands = pre
+			continue
+		}
+
+		for i, p := range panes {
+			// Named panes are maps with the pane title as their only key
+			if named, ok := importMap(p); ok {
+				for _, commands := range named {
+					p = commands
+				}
+			}
+
+			commands := append(append([]string{}, pre...), importCommands(p)...)
+			if i == 0 {
+				window.Commands = commands
+				continue
+			}
+			window.Panes = append(window.Panes, Pane{Commands: commands})
+		}
+	}
+
+	return window, warnings
+}
+
+// tmuxinatorTmuxOptions maps the tmux command line options of the project.
+func tmuxinatorTmuxOptions(config *Config, tmuxOptions string) []string {
+	var warnings []string
+
+	args := strings.Fields(tmuxOptions)
+	for i := 0; i < len(args); i++ {
+		var value string
+		if i+1 < len(args) {
+			value = args[i+1]
+		}
+
+		switch args[i] {
+		case "-f":
+			config.ConfigFile = value
+			i++
+		case "-L":
+			config.SocketName = value
+			i++
+		case "-S":
+			config.SocketPath = value
+			i++
+		default:
+			warnings = append(warnings, fmt.Sprintf("tmux_options %s is not supported", args[i]))
+		}
+	}
+
+	return warnings
+}
+
+// selectStartupWindow marks the window selected by its name or its index as
+// the selected one.
+func selectStartupWindow(windows []Window, startup string) {
+	for i := range windows {
+		if windows[i].Name == startup {
+			windows[i].Selected = true
+			return
+		}
+	}
+
+	if i, err := strconv.Atoi(startup); err == nil && i >= 0 && i < len(windows) {
+		windows[i].Selected = true
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+// ImportTmuxp converts a tmuxp session file, in YAML or JSON.
+func ImportTmuxp(path string, options *Options) ([]Config, []string, error) {
+	data, err := readImportFile(path)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	warnings := unmappedKeys("", data, []string{
+		"session_name", "start_directory", "before_script", "shell_command_before",
+		"environment", "windows",
+	})
+
+	config := Config{
+		Session:     importString(data["session_name"]),
+		Root:        importString(data["start_directory"]),
+		BeforeStart: importCommands(data["before_script"]),
+	}
+	if config.Session == "" {
+		config.Session = sessionNameFromPath(path)
+	}
+
+	if environment, ok := importMap(data["environment"]); ok {
+		config.Env = make(map[string]string, len(environment))
+		for key, value := range environment {
+			config.Env[key] = importString(value)
+		}
+	}
+
+	sessionBefore := importCommands(data["shell_command_before"])
+
+	windows, _ := data["windows"].([]any)
+	for i, w := range windows {
+		options, _ := importMap(w)
+		window, windowWarnings := tmuxpWindow(i, options, sessionBefore)
+		warnings = append(warnings, windowWarnings...)
+		config.Windows = append(config.Windows, window)
+	}
+
+	return []Config{config}, warnings, nil
+}
+
+func tmuxpWindow(index int, options map[string]any, sessionBefore []string) (Window, []string) {
+	window := Window{
+		Name:   importString(options["window_name"]),
+		Layout: importString(options["layout"]),
+		Root:   importString(options["start_directory"]),
+	}
+	if focus, ok := options["focus"].(bool); ok {
+		window.Selected = focus
+	}
+
+	prefix := "windows." + window.Name + "."
+	if window.Name == "" {
+		prefix = "windows." + importString(index) + "."
+	}
+	warnings := unmappedKeys(prefix, options, []string{
+		"window_name", "layout", "start_directory", "focus", "shell_command_before", "panes",
+	})
+
+	before := append(append([]string{}, sessionBefore...), importCommands(options["shell_command_before"])...)
+
+	panes, _ := options["panes"].([]any)
+	if len(panes) == 0 {
+		window.Commands = before
+		return window, warnings
+	}
+
+	for i, p := range panes {
+		pane := Pane{}
+
+		if paneOptions, ok := importMap(p); ok {
+			warnings = append(warnings, unmappedKeys(prefix+"panes.", paneOptions, []string{
+				"shell_command", "start_directory",
+			})...)
+			pane.Root = importString(paneOptions["start_directory"])
+			p = paneOptions["shell_command"]
+		}
+
+		// "blank" and "pane" are placeholders for an empty pane
+		if s, ok := p.(string); ok && (s == "blank" || s == "pane") {
+			p = nil
+		}
+
+		pane.Commands = append(append([]string{}, before...), importCommands(p)...)
+
+		if i == 0 {
+			window.Commands = pane.Commands
+			if pane.Root != "" {
+				warnings = append(warnings, prefix+"panes.start_directory of the first pane is not supported, use the window start_directory")
+			}
+			continue
+		}
+		window.Panes = append(window.Panes, pane)
+	}
+
+	return window, warnings
+}
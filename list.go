@@ -0,0 +1,196 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// ProjectInfo describes a project config and the state of its session.
+type ProjectInfo struct {
+	// Name is the name starting the project, group/member for the members
+	// of a group
+	Name    string `json:"name"`
+	Group   string `json:"group,omitempty"`
+	Path    string `json:"path"`
+	Session string `json:"session"`
+	Windows int    `json:"windows"`
+	Root    string `json:"root"`
+	Running bool   `json:"running"`
+
+	Description string   `json:"description,omitempty"`
+	Tags        []string `json:"tags,omitempty"`
+	Aliases     []string `json:"aliases,omitempty"`
+
+	// Socket is the tmux server of the session, empty for the default one
+	Socket string `json:"socket,omitempty"`
+	// Error is set when the config can't be read
+	Error string `json:"error,omitempty"`
+}
+
+// ProjectConfig is the config of a project, or of a member of a group.
+type ProjectConfig struct {
+	// Name is the name starting the project, group/member for the members
+	// of a group
+	Name  string
+	Group string
+	Path  string
+	// Err is set when the group can't be read
+	Err error
+}
+
+// ProjectConfigs returns the projects of the config dirs. A project found in
+// several dirs is returned once, from the first dir, the one smug start uses.
+func ProjectConfigs(configDirs []string) ([]ProjectConfig, error) {
+	var projects []ProjectConfig
+	seen := map[string]bool{}
+
+	for _, dir := range configDirs {
+		configs, err := ListConfigs(dir, true)
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+
+		for _, config := range configs {
+			name := strings.TrimSuffix(config, path.Ext(config))
+			if seen[name] {
+				continue
+			}
+			seen[name] = true
+
+			configPath := filepath.Join(dir, config)
+			if isDir, _ := IsDirectory(configPath); !isDir {
+				projects = append(projects, ProjectConfig{Name: name, Path: configPath})
+				continue
+			}
+
+			group, err := LoadGroup(configPath)
+			if err != nil {
+				projects = append(projects, ProjectConfig{Name: name, Path: configPath, Err: err})
+				continue
+			}
+
+			for _, member := range group.Members {
+				memberName := strings.TrimSuffix(filepath.Base(member), path.Ext(member))
+				projects = append(projects, ProjectConfig{
+					Name:  group.Name + "/" + memberName,
+					Group: group.Name,
+					Path:  member,
+				})
+			}
+		}
+	}
+
+	return projects, nil
+}
+
+// ListProjects describes the projects of the config dirs.
+func (smug Smug) ListProjects(configDirs []string) ([]ProjectInfo, error) {
+	configs, err := ProjectConfigs(configDirs)
+	if err != nil {
+		return nil, err
+	}
+
+	projects := []ProjectInfo{}
+	running := map[TmuxOptions][]string{}
+	for _, c := range configs {
+		if c.Err != nil {
+			projects = append(projects, ProjectInfo{Name: c.Name, Path: c.Path, Error: c.Err.Error()})
+			continue
+		}
+
+		projects = append(projects, smug.projectInfo(c.Name, c.Group, c.Path, running))
+	}
+
+	return projects, nil
+}
+
+// projectInfo reads the config at path. The sessions of the tmux servers are
+// cached into running, since configs often share a server.
+func (smug Smug) projectInfo(name string, group string, path string, running map[TmuxOptions][]string) ProjectInfo {
+	info := ProjectInfo{Name: name, Group: group, Path: path}
+
+	tmuxOpts := &TmuxOptions{}
+	config, err := GetConfig(path, map[string]string{}, tmuxOpts)
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+
+	info.Session = config.Session
+	info.Windows = len(config.Windows)
+	info.Root = config.Root
+	info.Description = config.Description
+	info.Tags = config.Tags
+	info.Aliases = config.Aliases
+	info.Socket = tmuxOpts.SocketName
+	if tmuxOpts.SocketPath != "" {
+		info.Socket = tmuxOpts.SocketPath
+	}
+
+	key := TmuxOptions{SocketName: tmuxOpts.SocketName, SocketPath: tmuxOpts.SocketPath}
+	sessions, ok := running[key]
+	if !ok {
+		// Listing fails when the server is not running
+		sessions, _ = Tmux{smug.tmux.commander, &key}.ListSessions()
+		running[key] = sessions
+	}
+
+	for _, session := range sessions {
+		if session == config.Session {
+			info.Running = true
+		}
+	}
+
+	return info
+}
+
+// PrintProjects writes the projects as a table.
+func PrintProjects(w io.Writer, projects []ProjectInfo) error {
+	var table strings.Builder
+	tw := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tSESSION\tSTATUS\tWINDOWS\tROOT\tDESCRIPTION")
+
+	for _, p := range projects {
+		if p.Error != "" {
+			fmt.Fprintf(tw, "%s\t-\tinvalid: %s\t-\t-\t\n", p.Name, strings.ReplaceAll(p.Error, "\n", " "))
+			continue
+		}
+
+		status := "stopped"
+		if p.Running {
+			status = "running"
+			if p.Socket != "" {
+				status += " (" + p.Socket + ")"
+			}
+		}
+
+		root := p.Root
+		if root == "" {
+			root = "-"
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%d\t%s\t%s\n", p.Name, p.Session, status, p.Windows, root, p.Description)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	// Projects without a description leave the padding of the root
+	for _, line := range strings.Split(strings.TrimSuffix(table.String(), "\n"), "\n") {
+		if _, err := fmt.Fprintln(w, strings.TrimRight(line, " ")); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestListProjects(t *testing.T) {
+	user := t.TempDir()
+	shared := t.TempDir()
+
+	files := map[string]string{
+		filepath.Join(user, "blog.yml"):              "session: blog\nroot: ~/blog\nwindows:\n  - name: code\n  - name: server\n",
+		filepath.Join(user, "api.yml"):               "session: api\ntmux_options:\n  socket_name: work\n",
+		filepath.Join(user, "broken.yml"):            "session: [\n",
+		filepath.Join(user, "backend", "db.yml"):     "session: db\n",
+		filepath.Join(user, "backend", "worker.yml"): "session: worker\n",
+		filepath.Join(user, "backend", ".group.yml"): "order: [worker]\n",
+		filepath.Join(shared, "blog.yml"):            "session: shared-blog\n",
+		filepath.Join(shared, "docs.yml"):            "session: docs\n",
+	}
+	for file, content := range files {
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(file, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	commander := &runningCommander{running: map[string]string{"api": "", "blog": "", "worker": ""}}
+	smug := Smug{Tmux{commander, &TmuxOptions{}}, commander}
+
+	projects, err := smug.ListProjects([]string{user, shared})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, p := range projects {
+		names = append(names, p.Name)
+	}
+	expectedNames := []string{"api", "backend/worker", "backend/db", "blog", "broken", "docs"}
+	if !reflect.DeepEqual(expectedNames, names) {
+		t.Fatalf("expected %v, got %v", expectedNames, names)
+	}
+
+	expected := ProjectInfo{
+		Name:    "api",
+		Path:    filepath.Join(user, "api.yml"),
+		Session: "api",
+		Running: true,
+		Socket:  "work",
+	}
+	if !reflect.DeepEqual(expected, projects[0]) {
+		t.Errorf("expected %v, got %v", expected, projects[0])
+	}
+
+	if p := projects[1]; p.Group != "backend" || !p.Running {
+		t.Errorf("expected a running member of backend, got %v", p)
+	}
+
+	if p := projects[3]; p.Session != "blog" || p.Windows != 2 || p.Root != "~/blog" || !p.Running {
+		t.Errorf("expected the user blog config, got %v", p)
+	}
+
+	if p := projects[4]; p.Error == "" {
+		t.Errorf("expected an error for the broken config, got %v", p)
+	}
+}
+
+func TestPrintProjects(t *testing.T) {
+	var b strings.Builder
+	err := PrintProjects(&b, []ProjectInfo{
+		{Name: "api", Session: "api", Windows: 1, Running: true, Socket: "work"},
+		{Name: "blog", Session: "blog", Windows: 2, Root: "~/blog", Description: "Personal blog"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `NAME  SESSION  STATUS          WINDOWS  ROOT    DESCRIPTION
+api   api      running (work)  1        -
+blog  blog     stopped         2        ~/blog  Personal blog
+`
+	if b.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, b.String())
+	}
+}
@@ -2,15 +2,15 @@ package main
 
 import (
 	"bufio"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
 	"os"
-	"path"
 	"path/filepath"
+	"slices"
 	"strings"
-
-	"gopkg.in/yaml.v3"
+	"time"
 )
 
 var version = "[dev build]"
@@ -19,7 +19,7 @@ var usage = fmt.Sprintf(`Smug - tmux session manager. Version %s
 
 
 Usage:
-	smug <command> [<project>] [-f, --file <file>] [--worktree <worktree>] [-w, --windows <window>]... [-a, --attach] [-d, --debug] [--detach] [-i, --inside-current-session] [<key>=<value>]...
+	smug <command> [<project>...] [-f, --file <file>] [--worktree <worktree>] [-w, --windows <window>]... [-a, --attach] [-d, --debug] [--detach] [-i, --inside-current-session] [--session <session>] [--all] [--format <format>] [--scrollback <lines>] [--interval <duration>] [--keep <count>] [--latest] [--from <format>] [--panes] [--with-deps] [--attach-to <project>] [--json] [--running] [--tag <tag>]... [--stop] [--popup] [--recent] [--instance <instance>] [--create] [--remove-worktree] [--force] [--delete-branch] [--all-worktrees[=<glob>]] [<key>=<value>]...
 
 Options:
 	-f, --file %s
@@ -29,37 +29,96 @@ Options:
 	-i, --inside-current-session %s
 	-d, --debug %s
 	--detach %s
+	--session %s
+	--all %s
+	--format %s
+	--scrollback %s
+	--interval %s
+	--keep %s
+	--latest %s
+	--from %s
+	--panes %s
+	--with-deps %s
+	--attach-to %s
+	--json %s
+	--running %s
+	--tag %s
+	--stop %s
+	--popup %s
+	--recent %s
+	--instance %s
+	--create %s
+	--remove-worktree %s
+	--force %s
+	--delete-branch %s
+	--all-worktrees %s
 
 Commands:
-	list    list available project configurations
-	edit    edit project configuration
-	new     new project configuration
-	start   start project session
-	stop    stop project session
-	print   session configuration to stdout
-	rm      remove project configuration
-	switch  switch to a project session (alias for start -a)
+	list      list project configurations and the state of their sessions
+	edit      edit project configuration
+	new       new project configuration
+	start     start project session
+	stop      stop project session
+	print     session configuration to stdout
+	rm        remove project configuration
+	switch    switch to a project session (alias for start -a), picked with pick without a project
+	pick      pick a project or a running session with a fuzzy finder, and switch to it
+	menu      show a tmux menu starting, switching to or stopping projects
+	last      switch to the previously used project
+	worktrees list the git worktrees of a project and their sessions
+	save      save a running session, optionally with its scrollback
+	restore   restore a saved session
+	autosave  save all running smug sessions into a rotating history
+	export    export a project as a shell script or a tmux command file
+	import    import a tmuxinator, tmuxp or tmux-resurrect configuration, a Procfile, a compose file or npm scripts
 
 Examples:
 	$ smug list
+	$ smug list --running --json
+	$ smug list --recent
+	$ smug last
 	$ smug edit blog
 	$ smug new blog
 	$ smug start blog
 	$ smug start blog --worktree feature-x
+	$ smug start blog --worktree feature-y --create --from main
 	$ smug start blog:win1
 	$ smug start blog -w win1
 	$ smug start blog:win1,win2
 	$ smug stop blog
+	$ smug stop blog --worktree feature-y --remove-worktree --delete-branch
+	$ smug worktrees blog --json
+	$ smug start blog --all-worktrees='release/*'
+	$ smug stop blog --all-worktrees
+	$ smug start backend/api
+	$ smug stop web --with-deps
 	$ smug start blog --attach
+	$ smug start blog --instance review
+	$ smug stop blog@review
+	$ smug start api web worker --attach-to web
+	$ smug start --tag work
+	$ SMUG_PATH=~/dotfiles/smug smug start blog
+	$ generate-config | smug start -f - --detach
 	$ smug print > ~/.config/smug/blog.yml
+	$ smug print --session blog --format json
+	$ smug print --all
+	$ smug save blog --scrollback 1000
+	$ smug restore blog
+	$ smug autosave --interval 15m --keep 20
+	$ smug restore --latest
+	$ smug import --from tmuxinator ~/.config/tmuxinator/blog.yml
+	$ smug import --from resurrect
+	$ smug import compose ./docker-compose.yml
+	$ smug import npm package.json -w dev -w test --panes
+	$ smug export blog > blog.sh
+	$ smug export blog --format tmux > blog.tmux
 	$ smug rm blog
+	$ smug pick
+	$ tmux bind-key S run-shell -b 'smug menu'
 	$ smug switch blog
-`, version, FileUsage, WorktreeUsage, WindowsUsage, AttachUsage, InsideCurrentSessionUsage, DebugUsage, DetachUsage)
+`, version, FileUsage, WorktreeUsage, WindowsUsage, AttachUsage, InsideCurrentSessionUsage, DebugUsage, DetachUsage, SessionUsage, AllUsage, FormatUsage, ScrollbackUsage, IntervalUsage, KeepUsage, LatestUsage, FromUsage, PanesUsage, WithDepsUsage, AttachToUsage, JSONUsage, RunningUsage, TagUsage, StopUsage, PopupUsage, RecentUsage, InstanceUsage, CreateUsage, RemoveWorktreeUsage, ForceUsage, DeleteBranchUsage, AllWorktreesUsage)
 
-const (
-	defaultConfigFile = ".smug.yml"
-	logFile           = "smug.log"
-)
+const logFile = "smug.log"
 
 func newLogger(path string) *log.Logger {
 	logFile, err := os.Create(filepath.Join(path, logFile))
@@ -71,15 +130,13 @@ func newLogger(path string) *log.Logger {
 }
 
 // applyWorktree overrides the config root with the path of the git worktree
-// selected via --worktree.
-func applyWorktree(config *Config, worktree string, commander Commander) error {
-	root := ExpandPath(config.Root)
-	if root == "" {
-		cwd, err := os.Getwd()
-		if err != nil {
-			return err
-		}
-		root = cwd
+// selected via --worktree, and names the session after the worktree. With
+// --create, a missing worktree is added first. A worktree to remove is only
+// matched exactly.
+func applyWorktree(config *Config, options *Options, commander Commander) error {
+	root, err := worktreeRoot(config)
+	if err != nil {
+		return err
 	}
 
 	worktrees, err := GitWorktrees(commander, root)
@@ -87,23 +144,51 @@ func applyWorktree(config *Config, worktree string, commander Commander) error {
 		return err
 	}
 
-	path, err := FindWorktree(worktrees, worktree)
-	if err != nil {
-		return err
+	var wt Worktree
+	switch {
+	case options.RemoveWorktree:
+		// The worktree is removed, it must be the one named
+		var ok bool
+		wt, ok = findExactWorktree(worktrees, options.Worktree)
+		if !ok {
+			return fmt.Errorf("%w, --remove-worktree needs its exact branch or directory name", WorktreeNotFoundError{options.Worktree})
+		}
+	case options.Create:
+		// Only an exact match stops --create from adding the branch, a
+		// prefix of it may be the new branch
+		var ok bool
+		wt, ok = findExactWorktree(worktrees, options.Worktree)
+		if !ok && len(worktrees) > 0 {
+			// The main worktree, or the bare repository, is listed first
+			main := worktrees[0]
+			path := WorktreePath(config.WorktreePath, main, options.Worktree)
+
+			fmt.Println("Creating worktree " + path)
+			err = AddWorktree(commander, main.Path, path, options.Worktree, options.From)
+			if err != nil {
+				return err
+			}
+			wt = Worktree{Path: path, Branch: options.Worktree}
+		}
+	default:
+		wt, err = FindWorktree(worktrees, options.Worktree)
+		if err != nil {
+			return err
+		}
 	}
 
-	config.Root = path
+	setWorktree(config, wt)
 	return nil
 }
 
-func getConfigs(options *Options, userConfigDir string) []string {
+func getConfigs(options *Options, project string, configDirs []string) []string {
 	var configs []string
 	switch {
 	case options.Config != "":
 		configs = append(configs, options.Config)
 
-	case options.Project != "":
-		projectConfigs, err := FindConfigs(userConfigDir, options.Project)
+	case project != "":
+		projectConfigs, err := FindConfigs(configDirs, project)
 		if err != nil {
 			fmt.Fprint(os.Stderr, err.Error())
 			os.Exit(1)
@@ -115,20 +200,164 @@ func getConfigs(options *Options, userConfigDir string) []string {
 			fmt.Fprintln(os.Stderr, err.Error())
 			os.Exit(1)
 		}
-		configs = append(configs, filepath.Join(cwd, defaultConfigFile))
+
+		config, err := FindLocalConfig(cwd)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		configs = append(configs, config)
 	}
 
 	return configs
 }
 
+// groupAttachConfig returns the config whose session is attached after
+// starting the configs, the one the manifest names when the project is a
+// group.
+func groupAttachConfig(options *Options, project string, configDirs []string, configs []string) string {
+	if options.Config == "" && project != "" {
+		group, err := FindGroup(configDirs, project)
+		if err == nil && slices.Contains(configs, group.Attach) {
+			return group.Attach
+		}
+	}
+
+	return configs[len(configs)-1]
+}
+
+// projectInstance splits the instance off a project given as
+// project@instance, defaulting to the one of --instance. A project whose
+// own name contains "@" is not split.
+func projectInstance(options *Options, project string, configDirs []string) (string, string) {
+	name, instance := SplitInstance(project)
+	if instance != "" {
+		if _, err := FindConfigs(configDirs, project); err == nil {
+			name, instance = project, ""
+		}
+	}
+	if instance == "" {
+		instance = options.Instance
+	}
+
+	return name, instance
+}
+
+// recordProjects records the started projects in the history. Projects
+// started from a file are not recorded, there is no name to start them again
+// with.
+func recordProjects(projects []ProjectStart, options *Options, logger *log.Logger) {
+	if options.Config != "" || len(options.Windows) > 0 {
+		return
+	}
+
+	for _, p := range projects {
+		if p.Name == "" {
+			continue
+		}
+		if err := RecordVisit(HistoryFile(), p.Name, p.Session, time.Now()); err != nil && options.Debug {
+			logger.Println("cannot record the project in the history:", err)
+		}
+	}
+}
+
+// unseenConfigs returns the configs not seen yet for the instance, so a
+// project given twice, e.g. by name and by tag or as a member of a group,
+// runs once.
+func unseenConfigs(seen map[string]bool, configs []string, instance string) []string {
+	var unseen []string
+	for _, config := range configs {
+		key := filepath.Clean(config) + instanceSeparator + instance
+		if !seen[key] {
+			seen[key] = true
+			unseen = append(unseen, config)
+		}
+	}
+
+	return unseen
+}
+
+// selectWorktrees returns the config rooted in the worktree of --worktree,
+// or a config for each worktree of --all-worktrees.
+func selectWorktrees(config *Config, options *Options, commander Commander) ([]*Config, error) {
+	switch {
+	case options.AllWorktrees != "":
+		return WorktreeConfigs(config, options.AllWorktrees, commander)
+	case options.Worktree != "":
+		return []*Config{config}, applyWorktree(config, options, commander)
+	}
+
+	return []*Config{config}, nil
+}
+
+// checkSessionOption exits when --session would give the same name to the
+// sessions of several configs.
+func checkSessionOption(options *Options, configs []string) {
+	if options.Session != "" && (len(configs) > 1 || len(options.Projects) > 1 || options.AllWorktrees != "") {
+		fmt.Fprintln(os.Stderr, "--session names a single session, use --instance to run several projects again")
+		os.Exit(1)
+	}
+}
+
+// projectNames returns the projects given to the command, several ones for
+// start and stop.
+func projectNames(options *Options) []string {
+	if len(options.Projects) > 0 {
+		return options.Projects
+	}
+
+	return []string{options.Project}
+}
+
+// printAllSessions writes the config of every running session into a
+// separate file in the current directory.
+func printAllSessions(smug Smug, format string) error {
+	sessions, err := smug.tmux.ListSessions()
+	if err != nil {
+		return err
+	}
+
+	ext := ".yml"
+	if format == FormatJSON {
+		ext = ".json"
+	}
+
+	for _, session := range sessions {
+		config, err := smug.ConfigFromSession(session)
+		if err != nil {
+			return err
+		}
+
+		d, err := MarshalConfig(config, format)
+		if err != nil {
+			return err
+		}
+
+		file := sessionFileName(session) + ext
+		if err := os.WriteFile(file, d, 0o644); err != nil {
+			return err
+		}
+		fmt.Println("Saved " + file)
+	}
+
+	return nil
+}
+
+// sessionFileName turns a session name into a name safe to use for a file.
+func sessionFileName(session string) string {
+	return strings.NewReplacer("/", "-", string(filepath.Separator), "-").Replace(session)
+}
+
 func main() {
-	userConfigDir := filepath.Join(ExpandPath("~/"), ".config/smug")
+	userConfigDir := UserConfigDir()
+	configDirs := ConfigDirs(userConfigDir)
 
 	// Create config Directory
 	if err := os.MkdirAll(userConfigDir, 0o750); err != nil {
 		fmt.Fprintf(
 			os.Stderr,
-			"Cannot initialize config dir at ~/.config/smug : %q",
+			"Cannot initialize config dir at %s : %q",
+			userConfigDir,
 			err.Error(),
 		)
 		os.Exit(1)
@@ -159,46 +388,268 @@ func main() {
 	smug := Smug{tmux, commander}
 	context := CreateContext()
 
-	switch options.Command {
-	case CommandStart, CommandSwitch:
-		configs := getConfigs(options, userConfigDir)
+	requirements := Requirements{
+		Find: func(project string) ([]string, error) {
+			return FindConfigs(configDirs, project)
+		},
+		Load: func(path string) (*Config, error) {
+			return GetConfig(path, options.Settings, &TmuxOptions{})
+		},
+	}
+
+	// Tagged projects are started and stopped along with the given ones
+	if len(options.Tags) > 0 && (options.Command == CommandStart || options.Command == CommandStop) {
+		tagged, err := FindTaggedProjects(configDirs, options.Tags)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		if len(options.Projects) == 0 && options.Project != "" {
+			options.Projects = []string{options.Project}
+		}
+		options.Projects = MergeTaggedProjects(configDirs, options.Projects, tagged)
+		options.Project = options.Projects[0]
+	}
+
+	// smug last switches back to the previously used project
+	if options.Command == CommandLast {
+		history, err := ReadHistory(HistoryFile())
+		if err != nil {
+			fmt.Fprint(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		current := ""
+		if context.InsideTmuxSession {
+			current, _ = smug.tmux.SessionName()
+		}
+
+		entry, ok := history.Last(current)
+		if !ok {
+			fmt.Fprint(os.Stderr, "no project was used before")
+			os.Exit(1)
+		}
+
+		// The session may be one of several of the project, e.g. the one of
+		// a worktree, it's switched to as long as it runs
+		if entry.Session != "" && smug.tmux.SessionExists(entry.Session+":") {
+			if err := RecordVisit(HistoryFile(), entry.Project, entry.Session, time.Now()); err != nil && options.Debug {
+				logger.Println("cannot record the project in the history:", err)
+			}
+
+			err := smug.switchOrAttach(entry.Session+":", true, context.InsideTmuxSession)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+
+		options.Command = CommandSwitch
+		options.Project = entry.Project
+		options.Config = ""
+		options.Attach = true
+	}
+
+	// smug pick, and smug switch without a project, let the project or the
+	// session to switch to be picked
+	if options.Command == CommandPick || (options.Command == CommandSwitch && options.Project == "") {
+		items, err := smug.PickerItems(configDirs)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err.Error())
+			os.Exit(1)
+		}
 
-		if options.Command == CommandSwitch && options.Project == "" {
-			fmt.Fprint(os.Stderr, "switch requires a project session")
+		item, err := Pick(items)
+		if errors.Is(err, ErrPickCancelled) {
+			os.Exit(0)
+		}
+		if err != nil {
+			fmt.Fprint(os.Stderr, err.Error())
 			os.Exit(1)
 		}
 
+		if item.Kind == PickSession {
+			err := smug.switchOrAttach(item.Name+":", true, context.InsideTmuxSession)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+
+		options.Command = CommandSwitch
+		options.Project = item.Name
+		options.Config = ""
+		options.Attach = true
+	}
+
+	switch options.Command {
+	case CommandStart, CommandSwitch:
 		if len(options.Windows) == 0 {
 			fmt.Println("Starting a new session...")
 		} else {
 			fmt.Println("Starting new windows...")
 		}
 
-		for configIndex, configPath := range configs {
-			config, err := GetConfig(configPath, options.Settings, smug.tmux.TmuxOptions)
+		// Several projects and the members of a group are started
+		// detached, and the session to attach to is attached once all of
+		// them run
+		var projects []ProjectStart
+		var attachTo *Config
+		started := map[string]bool{}
+
+		for _, project := range projectNames(options) {
+			name, instance := projectInstance(options, project, configDirs)
+			configs := getConfigs(options, name, configDirs)
+			attachConfig := groupAttachConfig(options, name, configDirs, configs)
+			checkSessionOption(options, configs)
+
+			configs = unseenConfigs(started, configs, instance)
+
+			if instance != "" {
+				project = name + instanceSeparator + instance
+			}
+
+			// With --all-worktrees, the sessions of each worktree are
+			// started as a project of their own
+			var starts []ProjectStart
+			worktreeStarts := map[string]int{}
+
+			for _, configPath := range configs {
+				config, err := GetConfig(configPath, options.Settings, &TmuxOptions{})
+				if err != nil {
+					fmt.Fprint(os.Stderr, err.Error())
+					os.Exit(1)
+				}
+
+				worktreeConfigs, err := selectWorktrees(config, options, smug.commander)
+				if err != nil {
+					fmt.Fprint(os.Stderr, err.Error())
+					os.Exit(1)
+				}
+
+				for _, config := range worktreeConfigs {
+					ApplyInstance(config, options.Session, instance)
+
+					i, ok := worktreeStarts[config.Env["SMUG_WORKTREE"]]
+					if !ok {
+						i = len(starts)
+						worktreeStarts[config.Env["SMUG_WORKTREE"]] = i
+						starts = append(starts, ProjectStart{Name: project})
+					}
+
+					starts[i].Configs = append(starts[i].Configs, config)
+					if configPath == attachConfig {
+						starts[i].Session = config.Session
+					}
+
+					switch {
+					case options.AttachTo == "" && configPath == attachConfig,
+						options.AttachTo == project && configPath == attachConfig,
+						options.AttachTo == config.Session:
+						attachTo = config
+					}
+				}
+			}
+			projects = append(projects, starts...)
+		}
+
+		if options.AttachTo != "" && attachTo == nil {
+			fmt.Fprintf(os.Stderr, "%s is not one of the started projects", options.AttachTo)
+			os.Exit(1)
+		}
+
+		// Requirements are started first and one at a time, since several
+		// projects may require the same one
+		for _, p := range projects {
+			for _, config := range p.Configs {
+				err := smug.StartRequirements(config, requirements, context)
+				if err != nil {
+					fmt.Fprint(os.Stderr, err.Error())
+					os.Exit(1)
+				}
+			}
+		}
+
+		if len(projects) == 1 && len(projects[0].Configs) == 1 {
+			config := projects[0].Configs[0]
+
+			// Outside tmux, attaching returns once the client detaches, so
+			// the session is started detached and recorded before attaching
+			startOptions := options
+			attach := !options.Detach && !options.InsideCurrentSession && len(options.Windows) == 0
+			if attach {
+				detached := *options
+				detached.Detach = true
+				startOptions = &detached
+			}
+
+			err := smug.StartConfigs(projects[0].Configs, startOptions, context)
 			if err != nil {
-				fmt.Fprint(os.Stderr, err.Error())
+				fmt.Println("Oops, an error occurred! Rolling back...")
 				os.Exit(1)
 			}
+			recordProjects(projects, options, logger)
 
-			if options.Worktree != "" {
-				if err := applyWorktree(config, options.Worktree, smug.commander); err != nil {
+			if attach {
+				err := smug.forConfig(config).switchOrAttach(config.Session+":", options.Attach || config.Attach, context.InsideTmuxSession)
+				if err != nil {
 					fmt.Fprint(os.Stderr, err.Error())
 					os.Exit(1)
 				}
 			}
+			break
+		}
 
-			options.Detach = options.Detach || (configIndex != len(configs)-1)
+		errs := smug.StartProjects(projects, options, context, maxParallelStarts)
 
-			err = smug.Start(config, options, context)
+		var startedProjects []ProjectStart
+		for i, p := range projects {
+			if errs[i] == nil {
+				startedProjects = append(startedProjects, p)
+			}
+		}
+		recordProjects(startedProjects, options, logger)
+
+		if err := errors.Join(errs...); err != nil {
+			fmt.Fprintf(os.Stderr, "Some sessions failed to start and were rolled back:\n%s\n", err)
+			os.Exit(1)
+		}
+
+		if !options.Detach {
+			attach := options.Attach || attachTo.Attach
+			err := smug.forConfig(attachTo).switchOrAttach(attachTo.Session+":", attach, context.InsideTmuxSession)
 			if err != nil {
-				fmt.Println("Oops, an error occurred! Rolling back...")
-				smug.Stop(config, options, context)
+				fmt.Fprint(os.Stderr, err.Error())
 				os.Exit(1)
 			}
 		}
 	case CommandStop:
-		configs := getConfigs(options, userConfigDir)
+		type instanceConfig struct {
+			path     string
+			instance string
+		}
+
+		var configs []instanceConfig
+		stopped := map[string]bool{}
+		for _, project := range projectNames(options) {
+			name, instance := projectInstance(options, project, configDirs)
+			paths := getConfigs(options, name, configDirs)
+			checkSessionOption(options, paths)
+
+			paths = unseenConfigs(stopped, paths, instance)
+
+			for _, path := range paths {
+				configs = append(configs, instanceConfig{path, instance})
+			}
+		}
+
+		if options.RemoveWorktree && (options.Worktree == "" || options.AllWorktrees != "" || len(options.Windows) > 0) {
+			fmt.Fprint(os.Stderr, "--remove-worktree needs --worktree and stops the whole session")
+			os.Exit(1)
+		}
 
 		if len(options.Windows) == 0 {
 			fmt.Println("Terminating session...")
@@ -206,64 +657,198 @@ func main() {
 			fmt.Println("Killing windows...")
 		}
 
-		for _, configPath := range configs {
-			config, err := GetConfig(configPath, options.Settings, smug.tmux.TmuxOptions)
+		// Projects and the members of a group are stopped in the reverse
+		// start order
+		slices.Reverse(configs)
+
+		var worktrees []string
+
+		for _, c := range configs {
+			config, err := GetConfig(c.path, options.Settings, smug.tmux.TmuxOptions)
 			if err != nil {
 				fmt.Fprint(os.Stderr, err.Error())
 				os.Exit(1)
 			}
 
-			if options.Worktree != "" {
-				if err := applyWorktree(config, options.Worktree, smug.commander); err != nil {
+			worktreeConfigs, err := selectWorktrees(config, options, smug.commander)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+
+			for _, config := range worktreeConfigs {
+				ApplyInstance(config, options.Session, c.instance)
+
+				// Only some worktrees may run, and a worktree left behind by
+				// a stopped session can still be removed
+				if (options.AllWorktrees != "" || options.RemoveWorktree) && !smug.tmux.SessionExists(config.Session+":") {
+					if options.RemoveWorktree {
+						worktrees = append(worktrees, config.Env["SMUG_WORKTREE"])
+					}
+					continue
+				}
+
+				err = smug.Stop(config, options, context)
+				if err != nil {
 					fmt.Fprint(os.Stderr, err.Error())
 					os.Exit(1)
 				}
+
+				if options.WithDeps && len(options.Windows) == 0 {
+					stopped, err := smug.StopRequirements(config, requirements, context)
+					for _, session := range stopped {
+						fmt.Println("Stopped " + session)
+					}
+					if err != nil {
+						fmt.Fprint(os.Stderr, err.Error())
+						os.Exit(1)
+					}
+				}
+
+				if options.RemoveWorktree {
+					worktrees = append(worktrees, config.Env["SMUG_WORKTREE"])
+				}
+			}
+		}
+
+		// Several configs may share the worktree, it's removed once all of
+		// them are stopped
+		removed := map[string]bool{}
+		for _, path := range worktrees {
+			if removed[path] {
+				continue
 			}
+			removed[path] = true
 
-			err = smug.Stop(config, options, context)
+			fmt.Println("Removing worktree " + path)
+			err := RemoveWorktree(smug.commander, path, options.DeleteBranch, options.Force)
 			if err != nil {
 				fmt.Fprint(os.Stderr, err.Error())
 				os.Exit(1)
 			}
 		}
+	case CommandWorktrees:
+		configs := getConfigs(options, options.Project, configDirs)
+		configPath := groupAttachConfig(options, options.Project, configDirs, configs)
+
+		config, err := GetConfig(configPath, options.Settings, &TmuxOptions{})
+		if err != nil {
+			fmt.Fprint(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		worktrees, err := smug.ListWorktrees(config)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		if options.JSON {
+			data, err := json.MarshalIndent(worktrees, "", "  ")
+			if err != nil {
+				fmt.Fprint(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+			fmt.Println(string(data))
+			break
+		}
+
+		err = PrintWorktrees(os.Stdout, worktrees)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err.Error())
+			os.Exit(1)
+		}
 	case CommandNew, CommandEdit:
-		err := EditConfig(filepath.Join(userConfigDir, options.Project+".yml"))
+		configPath := filepath.Join(userConfigDir, options.Project+".yml")
+		if path, err := FindConfigPath(configDirs, options.Project); err == nil {
+			configPath = path
+		}
+
+		err := EditConfig(configPath)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+	case CommandMenu:
+		if !context.InsideTmuxSession {
+			fmt.Fprint(os.Stderr, "menu must be run inside tmux, e.g. from a key binding")
+			os.Exit(1)
+		}
+
+		smugPath, err := os.Executable()
+		if err != nil {
+			fmt.Fprint(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		if options.Popup {
+			err = smug.tmux.DisplayPopup(shellQuote([]string{smugPath, "pick"}))
+		} else {
+			var projects []ProjectInfo
+			projects, err = smug.ListProjects(configDirs)
+			if err == nil {
+				title := "smug"
+				if options.Stop {
+					title = "Stop"
+				}
+				err = smug.tmux.DisplayMenu(title, ProjectMenu(projects, smugPath, options.Stop))
+			}
+		}
 		if err != nil {
 			fmt.Fprint(os.Stderr, err.Error())
 			os.Exit(1)
 		}
 	case CommandList:
-		configs, err := ListConfigs(userConfigDir, true)
+		projects, err := smug.ListProjects(configDirs)
 		if err != nil {
 			fmt.Fprint(os.Stderr, err.Error())
 			os.Exit(1)
 		}
 
-		for _, config := range configs {
-			fileExt := path.Ext(config)
-			fmt.Println(strings.TrimSuffix(config, fileExt))
-			isDir, err := IsDirectory(userConfigDir + "/" + config)
+		if options.Running {
+			projects = slices.DeleteFunc(projects, func(p ProjectInfo) bool { return !p.Running })
+		}
+
+		if options.Recent {
+			history, err := ReadHistory(HistoryFile())
 			if err != nil {
-				continue
+				fmt.Fprint(os.Stderr, err.Error())
+				os.Exit(1)
 			}
-			if isDir {
 
-				subConfigs, err := ListConfigs(userConfigDir+"/"+config, false)
-				if err != nil {
-					fmt.Fprint(os.Stderr, err.Error())
-					os.Exit(1)
-				}
-				for _, subConfig := range subConfigs {
-					fileExt := path.Ext(subConfig)
-					fmt.Println("|--" + strings.TrimSuffix(subConfig, fileExt))
+			var recent []ProjectInfo
+			for _, name := range history.Frecent(time.Now()) {
+				if i := slices.IndexFunc(projects, func(p ProjectInfo) bool { return p.Name == name }); i != -1 {
+					recent = append(recent, projects[i])
 				}
+			}
+			projects = recent
+		}
 
+		if len(options.Tags) > 0 {
+			projects = slices.DeleteFunc(projects, func(p ProjectInfo) bool {
+				return !slices.ContainsFunc(p.Tags, func(tag string) bool { return slices.Contains(options.Tags, tag) })
+			})
+		}
+
+		if options.JSON {
+			data, err := json.MarshalIndent(projects, "", "  ")
+			if err != nil {
+				fmt.Fprint(os.Stderr, err.Error())
+				os.Exit(1)
 			}
+			fmt.Println(string(data))
+			break
+		}
 
+		err = PrintProjects(os.Stdout, projects)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err.Error())
+			os.Exit(1)
 		}
 
 	case CommandRemove:
-		config, err := FindConfig(userConfigDir, options.Project)
+		configPath, err := FindConfigPath(configDirs, options.Project)
 		if err != nil {
 			fmt.Fprint(os.Stderr, err.Error())
 			os.Exit(1)
@@ -276,20 +861,162 @@ func main() {
 			return
 		}
 
-		err = RemoveConfig(filepath.Join(userConfigDir, config))
+		err = RemoveConfig(configPath)
 		if err != nil {
 			fmt.Fprint(os.Stderr, err.Error())
 			os.Exit(1)
 		}
 		fmt.Println("Removed " + options.Project)
+	case CommandSave:
+		session, err := smug.TargetSession(options, context)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		err = smug.SaveSession(session, filepath.Join(SessionsDir(), sessionFileName(session)), options.Scrollback)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+		fmt.Println("Saved " + session)
+	case CommandAutosave:
+		for {
+			sessions, err := smug.Autosave(AutosaveDir(), time.Now(), options.Scrollback, options.Keep)
+			switch {
+			case err != nil && options.Interval <= 0:
+				fmt.Fprint(os.Stderr, err.Error())
+				os.Exit(1)
+			case err != nil:
+				// A failed run is retried on the next tick
+				fmt.Fprintln(os.Stderr, err.Error())
+			}
+
+			if len(sessions) > 0 {
+				fmt.Println("Saved " + strings.Join(sessions, ", "))
+			}
+
+			if options.Interval <= 0 {
+				break
+			}
+			time.Sleep(options.Interval)
+		}
+	case CommandRestore:
+		if options.Latest {
+			snapshots, err := LatestSnapshot(AutosaveDir())
+			if err != nil {
+				fmt.Fprint(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+
+			fmt.Println("Restoring sessions...")
+			for snapshotIndex, snapshot := range snapshots {
+				config, err := LoadSnapshot(snapshot, smug.tmux.TmuxOptions)
+				if err != nil {
+					fmt.Fprint(os.Stderr, err.Error())
+					os.Exit(1)
+				}
+
+				options.Detach = options.Detach || (snapshotIndex != len(snapshots)-1)
+
+				err = smug.Start(config, options, context)
+				if err != nil {
+					fmt.Println("Oops, an error occurred! Rolling back...")
+					smug.Stop(config, options, context)
+					os.Exit(1)
+				}
+			}
+			return
+		}
+
+		if options.Project == "" {
+			fmt.Fprint(os.Stderr, "restore requires a saved session name")
+			os.Exit(1)
+		}
+
+		config, err := LoadSnapshot(filepath.Join(SessionsDir(), sessionFileName(options.Project)), smug.tmux.TmuxOptions)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		fmt.Println("Restoring session...")
+		err = smug.Start(config, options, context)
+		if err != nil {
+			fmt.Println("Oops, an error occurred! Rolling back...")
+			smug.Stop(config, options, context)
+			os.Exit(1)
+		}
+	case CommandImport:
+		configs, warnings, err := Import(options.From, options.Project, options)
+		if err != nil {
+			fmt.Fprint(os.Stderr, err.Error())
+			os.Exit(1)
+		}
+
+		for _, warning := range warnings {
+			fmt.Fprintln(os.Stderr, "Warning: "+warning)
+		}
+
+		for _, config := range configs {
+			path, err := WriteConfig(userConfigDir, config)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+			fmt.Println("Imported " + path)
+		}
+	case CommandExport:
+		name, instance := projectInstance(options, options.Project, configDirs)
+		configs := getConfigs(options, name, configDirs)
+		checkSessionOption(options, configs)
+
+		format := options.Format
+		if format == "" {
+			format = FormatShell
+		}
+
+		for _, configPath := range configs {
+			config, err := GetConfig(configPath, options.Settings, smug.tmux.TmuxOptions)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+
+			if options.Worktree != "" {
+				if err := applyWorktree(config, options, smug.commander); err != nil {
+					fmt.Fprint(os.Stderr, err.Error())
+					os.Exit(1)
+				}
+			}
+
+			ApplyInstance(config, options.Session, instance)
+
+			script, err := Export(*config, options, smug.tmux.TmuxOptions, format)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+
+			fmt.Print(script)
+		}
 	case CommandPrint:
+		if options.All {
+			err := printAllSessions(smug, options.Format)
+			if err != nil {
+				fmt.Fprint(os.Stderr, err.Error())
+				os.Exit(1)
+			}
+			return
+		}
+
 		config, err := smug.GetConfigFromSession(options, context)
 		if err != nil {
 			fmt.Fprint(os.Stderr, err.Error())
 			os.Exit(1)
 		}
 
-		d, err := yaml.Marshal(&config)
+		d, err := MarshalConfig(config, options.Format)
 		if err != nil {
 			fmt.Fprint(os.Stderr, err.Error())
 			os.Exit(1)
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"strings"
+)
+
+// menuKeys are the keys of the menu items, q being the key closing a menu.
+const menuKeys = "1234567890abcdefghijklmnoprtuvwyz"
+
+// escapeFormat keeps tmux from expanding the # of a menu item as a format.
+func escapeFormat(s string) string {
+	return strings.ReplaceAll(s, "#", "##")
+}
+
+// smugCommand is a tmux command running smug, discarding its progress
+// messages so only the errors are shown.
+func smugCommand(smugPath string, args ...string) string {
+	return "run-shell " + scriptQuote(shellQuote(append([]string{smugPath}, args...))+" >/dev/null")
+}
+
+// ProjectMenu returns the items of a tmux menu switching to the projects,
+// starting the ones that are not running, or with stop, stopping the
+// running ones. smugPath is the smug binary the items run.
+func ProjectMenu(projects []ProjectInfo, smugPath string, stop bool) []MenuItem {
+	var items []MenuItem
+
+	for _, p := range projects {
+		if p.Error != "" || (stop && !p.Running) {
+			continue
+		}
+
+		name := "  " + p.Name
+		if p.Running {
+			name = "* " + p.Name
+		}
+
+		item := MenuItem{Name: escapeFormat(name)}
+		if len(items) < len(menuKeys) {
+			item.Key = string(menuKeys[len(items)])
+		}
+
+		switch {
+		case p.Running && p.Socket != "" && !stop:
+			// The client can't switch to the session of another tmux server
+			item.Name = "-" + escapeFormat(name+" ("+p.Socket+")")
+		case stop:
+			item.Command = smugCommand(smugPath, "stop", p.Name)
+		default:
+			// Switching through smug records the project in the history
+			item.Command = smugCommand(smugPath, "switch", p.Name)
+		}
+
+		items = append(items, item)
+	}
+
+	if len(items) == 0 {
+		if stop {
+			return []MenuItem{{Name: "-No running project"}}
+		}
+		return []MenuItem{{Name: "-No project"}}
+	}
+
+	if !stop {
+		items = append(items,
+			MenuItem{},
+			MenuItem{Name: "Pick...", Key: "/", Command: "display-popup -E -w 80% -h 80% " + scriptQuote(shellQuote([]string{smugPath, "pick"}))},
+			MenuItem{Name: "Stop...", Key: "x", Command: smugCommand(smugPath, "menu", "--stop")},
+		)
+	}
+
+	return items
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var menuProjects = []ProjectInfo{
+	{Name: "api", Session: "api", Running: true},
+	{Name: "blog", Session: "blog"},
+	{Name: "work/db", Session: "db", Running: true, Socket: "work"},
+	{Name: "broken", Error: "yaml: line 1"},
+}
+
+var projectMenuTestTable = map[string]struct {
+	projects []ProjectInfo
+	stop     bool
+	expected []MenuItem
+}{
+	"switch": {
+		projects: menuProjects,
+		expected: []MenuItem{
+			{Name: "* api", Key: "1", Command: "run-shell '/usr/bin/smug switch api >/dev/null'"},
+			{Name: "  blog", Key: "2", Command: "run-shell '/usr/bin/smug switch blog >/dev/null'"},
+			{Name: "-* work/db (work)", Key: "3"},
+			{},
+			{Name: "Pick...", Key: "/", Command: "display-popup -E -w 80% -h 80% '/usr/bin/smug pick'"},
+			{Name: "Stop...", Key: "x", Command: "run-shell '/usr/bin/smug menu --stop >/dev/null'"},
+		},
+	},
+	"stop": {
+		projects: menuProjects,
+		stop:     true,
+		expected: []MenuItem{
+			{Name: "* api", Key: "1", Command: "run-shell '/usr/bin/smug stop api >/dev/null'"},
+			{Name: "* work/db", Key: "2", Command: "run-shell '/usr/bin/smug stop work/db >/dev/null'"},
+		},
+	},
+	"nothing to stop": {
+		projects: menuProjects[1:2],
+		stop:     true,
+		expected: []MenuItem{{Name: "-No running project"}},
+	},
+}
+
+func TestProjectMenu(t *testing.T) {
+	for testDescription, params := range projectMenuTestTable {
+		t.Run(testDescription, func(t *testing.T) {
+			items := ProjectMenu(params.projects, "/usr/bin/smug", params.stop)
+			if !reflect.DeepEqual(params.expected, items) {
+				t.Errorf("expected %v, got %v", params.expected, items)
+			}
+		})
+	}
+}
+
+func TestDisplayMenu(t *testing.T) {
+	commander := &MockCommander{}
+	tmux := Tmux{commander, &TmuxOptions{}}
+
+	err := tmux.DisplayMenu("smug", []MenuItem{
+		{Name: "* api", Key: "1", Command: "switch-client -t =api:"},
+		{},
+		{Name: "-No running project"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "tmux display-menu -T smug -x C -y C * api 1 switch-client -t =api:  -No running project  "
+	if strings.Join(commander.Commands, "\n") != expected {
+		t.Errorf("expected %q, got %q", expected, commander.Commands)
+	}
+}
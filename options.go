@@ -2,22 +2,33 @@ package main
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"slices"
 	"strings"
+	"time"
 
 	"github.com/spf13/pflag"
 )
 
 const (
-	CommandStart  = "start"
-	CommandStop   = "stop"
-	CommandNew    = "new"
-	CommandEdit   = "edit"
-	CommandList   = "list"
-	CommandPrint  = "print"
-	CommandRemove = "rm"
-	CommandSwitch = "switch"
+	CommandStart     = "start"
+	CommandStop      = "stop"
+	CommandNew       = "new"
+	CommandEdit      = "edit"
+	CommandList      = "list"
+	CommandPrint     = "print"
+	CommandRemove    = "rm"
+	CommandSwitch    = "switch"
+	CommandSave      = "save"
+	CommandRestore   = "restore"
+	CommandAutosave  = "autosave"
+	CommandImport    = "import"
+	CommandExport    = "export"
+	CommandPick      = "pick"
+	CommandMenu      = "menu"
+	CommandLast      = "last"
+	CommandWorktrees = "worktrees"
 )
 
 type command struct {
@@ -60,6 +71,42 @@ var Commands = commands{
 		Name:    CommandSwitch,
 		Aliases: []string{"sw"},
 	},
+	{
+		Name:    CommandSave,
+		Aliases: []string{},
+	},
+	{
+		Name:    CommandRestore,
+		Aliases: []string{},
+	},
+	{
+		Name:    CommandAutosave,
+		Aliases: []string{},
+	},
+	{
+		Name:    CommandImport,
+		Aliases: []string{},
+	},
+	{
+		Name:    CommandExport,
+		Aliases: []string{},
+	},
+	{
+		Name:    CommandPick,
+		Aliases: []string{},
+	},
+	{
+		Name:    CommandMenu,
+		Aliases: []string{},
+	},
+	{
+		Name:    CommandLast,
+		Aliases: []string{},
+	},
+	{
+		Name:    CommandWorktrees,
+		Aliases: []string{"wt"},
+	},
 }
 
 func (c *commands) Resolve(v string) (*command, error) {
@@ -85,14 +132,38 @@ func (c *commands) FindByName(n string) *command {
 type Options struct {
 	Command              string
 	Project              string
+	Projects             []string
 	Config               string
 	Worktree             string
+	Session              string
+	Format               string
 	Windows              []string
 	Settings             map[string]string
 	Attach               bool
 	Detach               bool
 	Debug                bool
 	InsideCurrentSession bool
+	All                  bool
+	Scrollback           int
+	Interval             time.Duration
+	Keep                 int
+	Latest               bool
+	From                 string
+	Panes                bool
+	WithDeps             bool
+	AttachTo             string
+	JSON                 bool
+	Running              bool
+	Tags                 []string
+	Stop                 bool
+	Popup                bool
+	Recent               bool
+	Instance             string
+	Create               bool
+	RemoveWorktree       bool
+	Force                bool
+	DeleteBranch         bool
+	AllWorktrees         string
 }
 
 var (
@@ -104,10 +175,33 @@ const (
 	WindowsUsage              = "List of windows to start. If session exists, those windows will be attached to current session"
 	AttachUsage               = "Force switch client for a session"
 	DetachUsage               = "Detach tmux session. The same as -d flag in the tmux"
-	DebugUsage                = "Print all commands to ~/.config/smug/smug.log"
-	FileUsage                 = "A custom path to a config file"
+	DebugUsage                = "Print all commands to smug.log in the config directory"
+	FileUsage                 = "A custom path to a config file, or - to read it from stdin"
 	InsideCurrentSessionUsage = "Create all windows inside current session"
-	WorktreeUsage             = "Use the git worktree (by branch or directory name) as the session root"
+	WorktreeUsage             = "Use the git worktree (by branch, directory name or commit) as the session root, in the session <session>/<branch>"
+	SessionUsage              = "Name of the tmux session to start, stop, print or save"
+	AllUsage                  = "Print every running session into a separate file in the current directory"
+	FormatUsage               = "Output format: yaml or json for print, sh or tmux for export"
+	ScrollbackUsage           = "Number of lines of each pane's scrollback to save"
+	IntervalUsage             = "Keep autosaving sessions at this interval instead of saving them once"
+	KeepUsage                 = "Number of autosaved snapshots to keep (default 10)"
+	LatestUsage               = "Restore all the sessions from the latest autosave"
+	FromUsage                 = "Format of the imported file: tmuxinator, tmuxp, resurrect, procfile, compose or npm. With --create, the revision the new branch starts from"
+	PanesUsage                = "Import processes as panes of a single window instead of separate windows"
+	WithDepsUsage             = "Also stop the required sessions that no other running session requires"
+	AttachToUsage             = "Project to attach to when starting several projects (default the last one)"
+	JSONUsage                 = "List the projects or the worktrees as JSON"
+	RunningUsage              = "List only the projects whose session is running"
+	TagUsage                  = "Start, stop or list every project with this tag"
+	StopUsage                 = "Show a menu stopping the running projects"
+	PopupUsage                = "Show the picker in a popup instead of a menu"
+	RecentUsage               = "List only the used projects, the most used and recent first"
+	InstanceUsage             = "Start another instance of the project, in the session <session>@<instance>"
+	CreateUsage               = "Create the git worktree given with --worktree when it doesn't exist"
+	RemoveWorktreeUsage       = "Remove the git worktree given with --worktree once its session is stopped"
+	ForceUsage                = "Remove the worktree even with uncommitted changes or unpushed commits"
+	DeleteBranchUsage         = "Also delete the branch of the removed worktree"
+	AllWorktreesUsage         = "Start or stop a session for every git worktree, or for the ones whose branch matches --all-worktrees=<glob>"
 )
 
 func parseUserSettings(args []string) map[string]string {
@@ -142,6 +236,30 @@ func ParseOptions(argv []string) (*Options, error) {
 	detach := flags.Bool("detach", false, DetachUsage)
 	debug := flags.BoolP("debug", "d", false, DebugUsage)
 	insideCurrentSession := flags.BoolP("inside-current-session", "i", false, InsideCurrentSessionUsage)
+	session := flags.String("session", "", SessionUsage)
+	all := flags.Bool("all", false, AllUsage)
+	format := flags.String("format", "", FormatUsage)
+	scrollback := flags.Int("scrollback", 0, ScrollbackUsage)
+	interval := flags.Duration("interval", 0, IntervalUsage)
+	keep := flags.Int("keep", 0, KeepUsage)
+	latest := flags.Bool("latest", false, LatestUsage)
+	from := flags.String("from", "", FromUsage)
+	panes := flags.Bool("panes", false, PanesUsage)
+	withDeps := flags.Bool("with-deps", false, WithDepsUsage)
+	attachTo := flags.String("attach-to", "", AttachToUsage)
+	jsonOutput := flags.Bool("json", false, JSONUsage)
+	running := flags.Bool("running", false, RunningUsage)
+	tags := flags.StringArray("tag", nil, TagUsage)
+	stop := flags.Bool("stop", false, StopUsage)
+	popup := flags.Bool("popup", false, PopupUsage)
+	recent := flags.Bool("recent", false, RecentUsage)
+	instance := flags.String("instance", "", InstanceUsage)
+	create := flags.Bool("create", false, CreateUsage)
+	removeWorktree := flags.Bool("remove-worktree", false, RemoveWorktreeUsage)
+	force := flags.Bool("force", false, ForceUsage)
+	deleteBranch := flags.Bool("delete-branch", false, DeleteBranchUsage)
+	allWorktrees := flags.String("all-worktrees", "", AllWorktreesUsage)
+	flags.Lookup("all-worktrees").NoOptDefVal = AllWorktrees
 
 	err := flags.Parse(argv)
 	if err == pflag.ErrHelp {
@@ -152,32 +270,78 @@ func ParseOptions(argv []string) (*Options, error) {
 		return nil, err
 	}
 
+	if *worktree != "" && *allWorktrees != "" {
+		return nil, errors.New("cannot use --worktree with --all-worktrees")
+	}
+
+	if cmd.Name == CommandStart && *from != "" && !*create {
+		return nil, errors.New("--from only applies to the worktree created with --create")
+	}
+
+	// Positional arguments, without the command name
+	args := flags.Args()
+	if !errors.Is(cmdErr, ErrCommandNotFound) && len(args) > 0 {
+		args = args[1:]
+	}
+
+	// smug import <format> [<file>] is the same as smug import --from <format> [<file>]
+	if cmd.Name == CommandImport && *from == "" && len(args) > 0 {
+		*from = args[0]
+		args = args[1:]
+	}
+
 	var project string
-	if *config == "" {
-		if errors.Is(cmdErr, ErrCommandNotFound) {
-			project = argv[0]
-		} else if len(argv) > 1 {
-			project = argv[1]
+	if *config == "" && len(args) > 0 {
+		project = args[0]
+	}
+
+	// smug start and stop take several projects, the other arguments are
+	// settings
+	var projects []string
+	if *config == "" && (cmd.Name == CommandStart || cmd.Name == CommandStop) {
+		for _, arg := range args {
+			if !strings.Contains(arg, "=") {
+				projects = append(projects, arg)
+			}
 		}
 	}
 
-	// If config file flag is not set, and env is, use the env
+	if len(projects) > 1 {
+		for _, p := range projects {
+			if strings.Contains(p, ":") {
+				return nil, fmt.Errorf("cannot select the windows of %s when several projects are given", p)
+			}
+		}
+	} else {
+		projects = nil
+	}
+
+	// If config file flag is not set, and env is, use the env. The env of
+	// an instance or worktree session also names its instance or worktree
 	val, ok := os.LookupEnv("SMUG_SESSION_CONFIG_PATH")
-	if *config == "" && project == "" && ok {
+	if *config == "" && project == "" && len(*tags) == 0 && ok {
 		*config = val
+		if *instance == "" {
+			*instance = os.Getenv("SMUG_INSTANCE")
+		}
+		if *worktree == "" && *allWorktrees == "" {
+			*worktree = os.Getenv("SMUG_WORKTREE")
+		}
 	}
 
-	if strings.Contains(project, ":") {
+	// The argument of import is a file path, not a project and its windows
+	if cmd.Name != CommandImport && strings.Contains(project, ":") {
 		parts := strings.Split(project, ":")
 		project = parts[0]
 		wl := strings.Split(parts[1], ",")
 		windows = &wl
 	}
 
-	settings := parseUserSettings(flags.Args()[1:])
+	settings := parseUserSettings(args)
 
 	opts := &Options{
 		Project:              project,
+		Projects:             projects,
 		Config:               *config,
 		Worktree:             *worktree,
 		Command:              cmd.Name,
@@ -187,6 +351,29 @@ func ParseOptions(argv []string) (*Options, error) {
 		Detach:               *detach,
 		Debug:                *debug,
 		InsideCurrentSession: *insideCurrentSession,
+		Session:              *session,
+		Format:               *format,
+		All:                  *all,
+		Scrollback:           *scrollback,
+		Interval:             *interval,
+		Keep:                 *keep,
+		Latest:               *latest,
+		From:                 *from,
+		Panes:                *panes,
+		WithDeps:             *withDeps,
+		AttachTo:             *attachTo,
+		JSON:                 *jsonOutput,
+		Running:              *running,
+		Tags:                 *tags,
+		Stop:                 *stop,
+		Popup:                *popup,
+		Recent:               *recent,
+		Instance:             *instance,
+		Create:               *create,
+		RemoveWorktree:       *removeWorktree,
+		Force:                *force,
+		DeleteBranch:         *deleteBranch,
+		AllWorktrees:         *allWorktrees,
 	}
 
 	if cmd.Name == CommandSwitch {
@@ -188,6 +188,115 @@ var usageTestTable = []struct {
 		nil,
 		nil,
 	},
+	{
+		[]string{"print", "--session", "blog", "--format", "json"},
+		Options{
+			Command:  "print",
+			Session:  "blog",
+			Format:   "json",
+			Windows:  []string{},
+			Settings: map[string]string{},
+		},
+		nil,
+		nil,
+	},
+	{
+		[]string{"start", "blog", "--instance", "review"},
+		Options{
+			Command:  "start",
+			Project:  "blog",
+			Instance: "review",
+			Windows:  []string{},
+			Settings: map[string]string{},
+		},
+		nil,
+		nil,
+	},
+	{
+		[]string{"start", "blog", "--all-worktrees"},
+		Options{
+			Command:      "start",
+			Project:      "blog",
+			AllWorktrees: "*",
+			Windows:      []string{},
+			Settings:     map[string]string{},
+		},
+		nil,
+		nil,
+	},
+	{
+		[]string{"stop", "blog", "--all-worktrees=release/*"},
+		Options{
+			Command:      "stop",
+			Project:      "blog",
+			AllWorktrees: "release/*",
+			Windows:      []string{},
+			Settings:     map[string]string{},
+		},
+		nil,
+		nil,
+	},
+	{
+		[]string{"print", "--all"},
+		Options{
+			Command:  "print",
+			All:      true,
+			Windows:  []string{},
+			Settings: map[string]string{},
+		},
+		nil,
+		nil,
+	},
+	{
+		[]string{"import", "npm", "web/package.json", "-w", "dev", "--panes"},
+		Options{
+			Command:  "import",
+			Project:  "web/package.json",
+			From:     "npm",
+			Panes:    true,
+			Windows:  []string{"dev"},
+			Settings: map[string]string{},
+		},
+		nil,
+		nil,
+	},
+	{
+		[]string{"import", "compose", "/mnt/c:/work/compose.yml"},
+		Options{
+			Command:  "import",
+			Project:  "/mnt/c:/work/compose.yml",
+			From:     "compose",
+			Windows:  []string{},
+			Settings: map[string]string{},
+		},
+		nil,
+		nil,
+	},
+	{
+		[]string{"start", "api", "web", "worker", "--attach-to", "web", "env=dev"},
+		Options{
+			Command:  "start",
+			Project:  "api",
+			Projects: []string{"api", "web", "worker"},
+			AttachTo: "web",
+			Windows:  []string{},
+			Settings: map[string]string{"env": "dev"},
+		},
+		nil,
+		nil,
+	},
+	{
+		[]string{"start", "api:code", "web"},
+		Options{},
+		errors.New("cannot select the windows of api:code when several projects are given"),
+		nil,
+	},
+	{
+		[]string{"start", "blog", "--worktree", "feature-x", "--all-worktrees"},
+		Options{},
+		errors.New("cannot use --worktree with --all-worktrees"),
+		nil,
+	},
 	{
 		[]string{"start", "--help"},
 		Options{},
@@ -230,6 +339,51 @@ var usageTestTable = []struct {
 			"SMUG_SESSION_CONFIG_PATH": "test",
 		},
 	},
+	{
+		[]string{"stop"},
+		Options{
+			Command:  "stop",
+			Config:   "/home/user/.config/smug/blog.yml",
+			Instance: "review",
+			Windows:  []string{},
+			Settings: map[string]string{},
+		},
+		nil,
+		map[string]string{
+			"SMUG_SESSION_CONFIG_PATH": "/home/user/.config/smug/blog.yml",
+			"SMUG_INSTANCE":            "review",
+		},
+	},
+	{
+		[]string{"stop", "--instance", "other"},
+		Options{
+			Command:  "stop",
+			Config:   "/home/user/.config/smug/blog.yml",
+			Instance: "other",
+			Windows:  []string{},
+			Settings: map[string]string{},
+		},
+		nil,
+		map[string]string{
+			"SMUG_SESSION_CONFIG_PATH": "/home/user/.config/smug/blog.yml",
+			"SMUG_INSTANCE":            "review",
+		},
+	},
+	{
+		[]string{"start", "-w", "logs"},
+		Options{
+			Command:  "start",
+			Config:   "/home/user/.config/smug/blog.yml",
+			Worktree: "/home/user/dev/blog-feature",
+			Windows:  []string{"logs"},
+			Settings: map[string]string{},
+		},
+		nil,
+		map[string]string{
+			"SMUG_SESSION_CONFIG_PATH": "/home/user/.config/smug/blog.yml",
+			"SMUG_WORKTREE":            "/home/user/dev/blog-feature",
+		},
+	},
 	{
 		[]string{},
 		Options{},
@@ -291,6 +445,9 @@ func TestParseOptions(t *testing.T) {
 			os.Setenv(k, v)
 		}
 		opts, err := ParseOptions(v.argv)
+		for k := range v.env {
+			os.Unsetenv(k)
+		}
 		if v.err != nil && err != nil && err.Error() != v.err.Error() {
 			t.Errorf("expected error %v, got %v", v.err, err)
 		}
@@ -0,0 +1,388 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	PickProject = "project"
+	PickSession = "session"
+)
+
+// ErrPickCancelled is returned when the picker is closed without a choice.
+var ErrPickCancelled = errors.New("nothing picked")
+
+// PickerItem is a project or a running session offered by the picker.
+type PickerItem struct {
+	Kind    string
+	Name    string
+	Running bool
+	// Preview describes the windows of the config or of the session
+	Preview []string
+}
+
+// PickerItems returns the projects of the config dirs, followed by the
+// running sessions that no project starts.
+func (smug Smug) PickerItems(configDirs []string) ([]PickerItem, error) {
+	projects, err := smug.ListProjects(configDirs)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []PickerItem
+	sessions := map[string]bool{}
+	for _, p := range projects {
+		if p.Error != "" {
+			continue
+		}
+		if p.Socket == "" {
+			sessions[p.Session] = true
+		}
+		items = append(items, PickerItem{
+			Kind:    PickProject,
+			Name:    p.Name,
+			Running: p.Running,
+			Preview: projectPreview(p),
+		})
+	}
+
+	// Listing fails when no server is running
+	running, _ := smug.tmux.ListSessions()
+	for _, session := range running {
+		if sessions[session] {
+			continue
+		}
+
+		item := PickerItem{Kind: PickSession, Name: session, Running: true}
+		windows, err := smug.tmux.ListWindows(session + ":")
+		if err == nil {
+			for _, w := range windows {
+				item.Preview = append(item.Preview, "  "+w.Name+"  "+w.Root)
+			}
+		}
+		items = append(items, item)
+	}
+
+	return items, nil
+}
+
+func projectPreview(p ProjectInfo) []string {
+	var preview []string
+	if p.Description != "" {
+		preview = append(preview, p.Description, "")
+	}
+
+	preview = append(preview, "session: "+p.Session, "config:  "+p.Path)
+	if p.Root != "" {
+		preview = append(preview, "root:    "+p.Root)
+	}
+
+	config, err := GetConfig(p.Path, map[string]string{}, &TmuxOptions{})
+	if err != nil {
+		return preview
+	}
+
+	preview = append(preview, "", "windows:")
+	for _, w := range config.Windows {
+		line := "  " + w.Name
+		if len(w.Panes) > 0 {
+			line += fmt.Sprintf(" (%d panes)", len(w.Panes)+1)
+		}
+		preview = append(preview, line)
+		for _, c := range w.Commands {
+			preview = append(preview, "    $ "+c)
+		}
+	}
+
+	return preview
+}
+
+// fuzzyScore matches the characters of the query, in order, against text,
+// ignoring case. Matches at the start of words and runs of consecutive
+// characters score higher.
+func fuzzyScore(query string, text string) (int, bool) {
+	if query == "" {
+		return 0, true
+	}
+
+	q := []rune(strings.ToLower(query))
+	t := []rune(strings.ToLower(text))
+
+	score := 0
+	qi := 0
+	previous := -2
+	for ti := 0; ti < len(t) && qi < len(q); ti++ {
+		if t[ti] != q[qi] {
+			continue
+		}
+
+		score++
+		if ti == previous+1 {
+			score += 3
+		}
+		if ti == 0 || !unicode.IsLetter(t[ti-1]) && !unicode.IsDigit(t[ti-1]) {
+			score += 2
+		}
+
+		previous = ti
+		qi++
+	}
+
+	if qi < len(q) {
+		return 0, false
+	}
+
+	// Shorter names are closer matches
+	return score*100 - len(t), true
+}
+
+// filterItems returns the items matching the query, best matches first.
+func filterItems(items []PickerItem, query string) []PickerItem {
+	type match struct {
+		item  PickerItem
+		score int
+	}
+
+	var matches []match
+	for _, item := range items {
+		if score, ok := fuzzyScore(query, item.Name); ok {
+			matches = append(matches, match{item, score})
+		}
+	}
+
+	slices.SortStableFunc(matches, func(a, b match) int { return b.score - a.score })
+
+	filtered := make([]PickerItem, len(matches))
+	for i, m := range matches {
+		filtered[i] = m.item
+	}
+
+	return filtered
+}
+
+const (
+	keyEnter = iota + utf8.MaxRune + 1
+	keyCancel
+	keyBackspace
+	keyClear
+	keyUp
+	keyDown
+)
+
+// readKeys decodes the keys of a chunk read from a terminal in raw mode.
+func readKeys(chunk []byte) []rune {
+	var keys []rune
+	for len(chunk) > 0 {
+		switch {
+		case chunk[0] == 0x1b && len(chunk) >= 3 && (chunk[1] == '[' || chunk[1] == 'O'):
+			// The sequence ends with its final byte, after the parameters
+			// of keys such as ESC [3~ or ESC [1;5A
+			end := 2
+			for end < len(chunk)-1 && (chunk[end] < 0x40 || chunk[end] > 0x7e) {
+				end++
+			}
+			switch chunk[end] {
+			case 'A':
+				keys = append(keys, keyUp)
+			case 'B':
+				keys = append(keys, keyDown)
+			}
+			chunk = chunk[end+1:]
+			continue
+		case chunk[0] == 0x1b, chunk[0] == 3, chunk[0] == 4:
+			keys = append(keys, keyCancel)
+		case chunk[0] == '\r', chunk[0] == '\n':
+			keys = append(keys, keyEnter)
+		case chunk[0] == 127, chunk[0] == 8:
+			keys = append(keys, keyBackspace)
+		case chunk[0] == 21:
+			keys = append(keys, keyClear)
+		case chunk[0] == 16, chunk[0] == 11:
+			keys = append(keys, keyUp)
+		case chunk[0] == 14:
+			keys = append(keys, keyDown)
+		case chunk[0] >= 32:
+			r, size := utf8.DecodeRune(chunk)
+			keys = append(keys, r)
+			chunk = chunk[size:]
+			continue
+		}
+		chunk = chunk[1:]
+	}
+
+	return keys
+}
+
+// picker is the state of the fuzzy finder.
+type picker struct {
+	items    []PickerItem
+	query    string
+	filtered []PickerItem
+	selected int
+	rows     int
+	cols     int
+}
+
+// runPicker reads keys from in and draws the picker on out until an item is
+// picked or the picker is cancelled.
+func runPicker(items []PickerItem, in io.Reader, out io.Writer, rows int, cols int) (PickerItem, error) {
+	p := &picker{items: items, filtered: items, rows: rows, cols: cols}
+
+	buf := make([]byte, 64)
+	for {
+		p.draw(out)
+
+		n, err := in.Read(buf)
+		if err != nil {
+			return PickerItem{}, err
+		}
+
+		for _, key := range readKeys(buf[:n]) {
+			switch key {
+			case keyEnter:
+				if len(p.filtered) == 0 {
+					continue
+				}
+				return p.filtered[p.selected], nil
+			case keyCancel:
+				return PickerItem{}, ErrPickCancelled
+			case keyUp:
+				p.selected = max(p.selected-1, 0)
+			case keyDown:
+				p.selected = min(p.selected+1, max(len(p.filtered)-1, 0))
+			case keyBackspace:
+				if p.query != "" {
+					_, size := utf8.DecodeLastRuneInString(p.query)
+					p.setQuery(p.query[:len(p.query)-size])
+				}
+			case keyClear:
+				p.setQuery("")
+			default:
+				p.setQuery(p.query + string(key))
+			}
+		}
+	}
+}
+
+func (p *picker) setQuery(query string) {
+	p.query = query
+	p.filtered = filterItems(p.items, query)
+	p.selected = 0
+}
+
+// draw renders the prompt and the list on the left half of the screen, and
+// the preview of the selected item on the right half.
+func (p *picker) draw(out io.Writer) {
+	var b strings.Builder
+	b.WriteString("\x1b[H\x1b[2J")
+
+	listWidth := p.cols / 2
+	height := p.rows - 2
+
+	fmt.Fprintf(&b, "> %s\r\n", p.query)
+	fmt.Fprintf(&b, "\x1b[2m  %d/%d\x1b[0m\r\n", len(p.filtered), len(p.items))
+
+	// Scroll to keep the selected item visible
+	offset := 0
+	if p.selected >= height {
+		offset = p.selected - height + 1
+	}
+
+	for i := offset; i < len(p.filtered) && i-offset < height; i++ {
+		item := p.filtered[i]
+
+		marker := " "
+		if item.Running {
+			marker = "*"
+		}
+		line := truncate(fmt.Sprintf("%s %s [%s]", marker, item.Name, item.Kind), listWidth-2)
+
+		fmt.Fprintf(&b, "\x1b[%d;1H", i-offset+3)
+		if i == p.selected {
+			fmt.Fprintf(&b, "\x1b[7m> %s\x1b[0m", line)
+		} else {
+			fmt.Fprintf(&b, "  %s", line)
+		}
+	}
+
+	if len(p.filtered) > 0 {
+		for i, line := range p.filtered[p.selected].Preview {
+			if i >= p.rows-1 {
+				break
+			}
+			fmt.Fprintf(&b, "\x1b[%d;%dH\x1b[2m│\x1b[0m %s", i+2, listWidth+1, truncate(line, p.cols-listWidth-3))
+		}
+	}
+
+	fmt.Fprintf(&b, "\x1b[1;%dH", len([]rune(p.query))+3)
+	io.WriteString(out, b.String())
+}
+
+func truncate(s string, width int) string {
+	r := []rune(s)
+	if width <= 0 {
+		return ""
+	}
+	if len(r) > width {
+		return string(r[:width-1]) + "…"
+	}
+
+	return s
+}
+
+// Pick opens the fuzzy finder full screen on the terminal, and returns the
+// picked item.
+func Pick(items []PickerItem) (PickerItem, error) {
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return PickerItem{}, fmt.Errorf("the picker needs a terminal: %w", err)
+	}
+	defer tty.Close()
+
+	state, err := stty(tty, "-g")
+	if err != nil {
+		return PickerItem{}, err
+	}
+
+	rows, cols := 24, 80
+	if size, err := stty(tty, "size"); err == nil {
+		if fields := strings.Fields(size); len(fields) == 2 {
+			rows, _ = strconv.Atoi(fields[0])
+			cols, _ = strconv.Atoi(fields[1])
+		}
+	}
+
+	if _, err := stty(tty, "raw", "-echo"); err != nil {
+		return PickerItem{}, err
+	}
+
+	// Draw on the alternate screen, restoring the terminal afterwards
+	io.WriteString(tty, "\x1b[?1049h")
+	defer func() {
+		io.WriteString(tty, "\x1b[?1049l")
+		stty(tty, strings.TrimSpace(state))
+	}()
+
+	return runPicker(items, tty, tty, rows, cols)
+}
+
+func stty(tty *os.File, args ...string) (string, error) {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+
+	out, err := cmd.Output()
+	if err != nil {
+		return "", fmt.Errorf("stty %s: %w", strings.Join(args, " "), err)
+	}
+
+	return string(out), nil
+}
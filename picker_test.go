@@ -0,0 +1,142 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var filterItemsTestTable = map[string]struct {
+	query    string
+	expected []string
+}{
+	"empty query keeps the order": {
+		query:    "",
+		expected: []string{"frontend-monorepo", "backend/api", "blog", "infra"},
+	},
+	"word starts first": {
+		query:    "fm",
+		expected: []string{"frontend-monorepo"},
+	},
+	"shorter names first": {
+		query:    "b",
+		expected: []string{"blog", "backend/api"},
+	},
+	"case insensitive": {
+		query:    "API",
+		expected: []string{"backend/api"},
+	},
+	"no match": {
+		query:    "xyz",
+		expected: []string{},
+	},
+}
+
+func TestFilterItems(t *testing.T) {
+	var items []PickerItem
+	for _, name := range []string{"frontend-monorepo", "backend/api", "blog", "infra"} {
+		items = append(items, PickerItem{Kind: PickProject, Name: name})
+	}
+
+	for testDescription, params := range filterItemsTestTable {
+		t.Run(testDescription, func(t *testing.T) {
+			names := []string{}
+			for _, item := range filterItems(items, params.query) {
+				names = append(names, item.Name)
+			}
+
+			if !reflect.DeepEqual(params.expected, names) {
+				t.Errorf("expected %v, got %v", params.expected, names)
+			}
+		})
+	}
+}
+
+func TestReadKeys(t *testing.T) {
+	keys := readKeys([]byte("ab\x1b[B\x1b[3~c\x1b[1;5A\x1bOB\x7f\x15é\r\x1b"))
+	expected := []rune{'a', 'b', keyDown, 'c', keyUp, keyDown, keyBackspace, keyClear, 'é', keyEnter, keyCancel}
+	if !reflect.DeepEqual(expected, keys) {
+		t.Errorf("expected %v, got %v", expected, keys)
+	}
+}
+
+// keyReader returns one chunk of keys per read, like a terminal.
+type keyReader struct {
+	chunks []string
+}
+
+func (r *keyReader) Read(p []byte) (int, error) {
+	if len(r.chunks) == 0 {
+		return 0, io.EOF
+	}
+
+	n := copy(p, r.chunks[0])
+	r.chunks = r.chunks[1:]
+	return n, nil
+}
+
+var runPickerTestTable = map[string]struct {
+	keys     []string
+	expected string
+	err      error
+}{
+	"first item": {
+		keys:     []string{"\r"},
+		expected: "blog",
+	},
+	"filtered": {
+		keys:     []string{"w", "o", "\r"},
+		expected: "work",
+	},
+	"moved past the end and back": {
+		keys:     []string{"\x1b[B", "\x1b[B", "\x1b[B", "\x1b[A", "\r"},
+		expected: "work",
+	},
+	"query edited": {
+		keys:     []string{"x", "\x7f", "dot", "\r"},
+		expected: "dotfiles",
+	},
+	"nothing matches": {
+		keys: []string{"xyz", "\r", "\x1b"},
+		err:  ErrPickCancelled,
+	},
+	"cancelled": {
+		keys: []string{"\x03"},
+		err:  ErrPickCancelled,
+	},
+}
+
+func TestRunPicker(t *testing.T) {
+	items := []PickerItem{
+		{Kind: PickProject, Name: "blog", Preview: []string{"windows:", "  code"}},
+		{Kind: PickProject, Name: "work", Running: true},
+		{Kind: PickSession, Name: "dotfiles", Running: true},
+	}
+
+	for testDescription, params := range runPickerTestTable {
+		t.Run(testDescription, func(t *testing.T) {
+			var out strings.Builder
+			item, err := runPicker(items, &keyReader{chunks: params.keys}, &out, 24, 80)
+
+			if params.err != nil {
+				if !errors.Is(err, params.err) {
+					t.Errorf("expected error %v, got %v", params.err, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if item.Name != params.expected {
+				t.Errorf("expected %s, got %s", params.expected, item.Name)
+			}
+
+			if !strings.Contains(out.String(), "* work [project]") {
+				t.Errorf("expected running projects to be marked, got %q", out.String())
+			}
+		})
+	}
+}
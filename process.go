@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// procDir is the mount point of the proc filesystem. It's a variable so tests
+// can point it to a fake tree.
+var procDir = "/proc"
+
+// childProcesses returns the pids of the direct children of pid, in the order
+// they were started.
+func childProcesses(pid int) ([]int, error) {
+	entries, err := os.ReadDir(procDir)
+	if err != nil {
+		return nil, err
+	}
+
+	var children []int
+	for _, e := range entries {
+		child, err := strconv.Atoi(e.Name())
+		if err != nil {
+			continue
+		}
+
+		stat, err := os.ReadFile(filepath.Join(procDir, e.Name(), "stat"))
+		if err != nil {
+			continue
+		}
+
+		// The second field is the command name in parentheses and may contain
+		// spaces, so the fields we need start after the last ')'
+		s := string(stat)
+		fields := strings.Fields(s[strings.LastIndex(s, ")")+1:])
+		if len(fields) < 2 {
+			continue
+		}
+
+		if ppid, err := strconv.Atoi(fields[1]); err == nil && ppid == pid {
+			children = append(children, child)
+		}
+	}
+
+	sort.Ints(children)
+	return children, nil
+}
+
+// processArgs returns the argv of the process with the given pid.
+func processArgs(pid int) ([]string, error) {
+	cmdline, err := os.ReadFile(filepath.Join(procDir, strconv.Itoa(pid), "cmdline"))
+	if err != nil {
+		return nil, err
+	}
+
+	return strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00"), nil
+}
+
+// shellQuote joins args into a single command line, quoting the ones that
+// the shell would otherwise split or expand.
+func shellQuote(args []string) string {
+	quoted := make([]string, len(args))
+	for i, a := range args {
+		if a != "" && strings.IndexFunc(a, needsQuoting) == -1 {
+			quoted[i] = a
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(a, "'", `'\''`) + "'"
+	}
+
+	return strings.Join(quoted, " ")
+}
+
+func needsQuoting(r rune) bool {
+	return !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || strings.ContainsRune("-_./=:,+@%", r))
+}
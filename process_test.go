@@ -0,0 +1,22 @@
+package main
+
+import "testing"
+
+func TestShellQuote(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"htop"}, "htop"},
+		{[]string{"npm", "run", "dev", "--port=3000"}, "npm run dev --port=3000"},
+		{[]string{"sh", "-c", "echo $HOME"}, "sh -c 'echo $HOME'"},
+		{[]string{"echo", "it's"}, `echo 'it'\''s'`},
+		{[]string{"echo", ""}, "echo ''"},
+	}
+
+	for _, tt := range tests {
+		if got := shellQuote(tt.args); got != tt.want {
+			t.Errorf("shellQuote(%q) = %q, want %q", tt.args, got, tt.want)
+		}
+	}
+}
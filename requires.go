@@ -0,0 +1,162 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Requirements resolves the projects named by the requires option of configs.
+type Requirements struct {
+	// Find returns the config paths of a project
+	Find func(project string) ([]string, error)
+	// Load reads the config at path
+	Load func(path string) (*Config, error)
+}
+
+// resolve returns the configs required by config, directly or not, each one
+// after its own requirements.
+func (r Requirements) resolve(config *Config) ([]*Config, error) {
+	var resolved []*Config
+	err := r.visit(config, []string{config.Session}, &resolved)
+
+	return resolved, err
+}
+
+func (r Requirements) visit(config *Config, chain []string, resolved *[]*Config) error {
+	for _, project := range config.Requires {
+		paths, err := r.Find(project)
+		if err != nil {
+			return fmt.Errorf("%s requires %s: %w", config.Session, project, err)
+		}
+
+		for _, path := range paths {
+			required, err := r.Load(path)
+			if err != nil {
+				return fmt.Errorf("%s requires %s: %w", config.Session, project, err)
+			}
+
+			if slices.Contains(chain, required.Session) {
+				return fmt.Errorf("dependency cycle: %s -> %s", strings.Join(chain, " -> "), required.Session)
+			}
+
+			if slices.ContainsFunc(*resolved, func(c *Config) bool { return c.Session == required.Session }) {
+				continue
+			}
+
+			err = r.visit(required, append(slices.Clone(chain), required.Session), resolved)
+			if err != nil {
+				return err
+			}
+
+			*resolved = append(*resolved, required)
+		}
+	}
+
+	return nil
+}
+
+// forConfig returns smug using the tmux server of the config, which may not
+// be the one of the config requiring it.
+func (smug Smug) forConfig(config *Config) Smug {
+	tmuxOpts := &TmuxOptions{}
+	setTmuxOptions(tmuxOpts, *config)
+
+	return Smug{Tmux{smug.tmux.commander, tmuxOpts}, smug.commander}
+}
+
+// StartRequirements starts the sessions required by the config that are not
+// running yet, detached, dependencies first.
+func (smug Smug) StartRequirements(config *Config, requirements Requirements, context Context) error {
+	required, err := requirements.resolve(config)
+	if err != nil {
+		return err
+	}
+
+	for _, c := range required {
+		s := smug.forConfig(c)
+		if s.tmux.SessionExists(c.Session + ":") {
+			continue
+		}
+
+		err := s.Start(c, &Options{Detach: true}, context)
+		if err != nil {
+			return fmt.Errorf("start %s: %w", c.Session, err)
+		}
+	}
+
+	return nil
+}
+
+// StopRequirements stops the sessions required by the config, dependents
+// first, except the ones another running session still requires. It returns
+// the stopped sessions.
+func (smug Smug) StopRequirements(config *Config, requirements Requirements, context Context) ([]string, error) {
+	required, err := requirements.resolve(config)
+	if err != nil {
+		return nil, err
+	}
+
+	needed, err := smug.neededSessions(required, requirements)
+	if err != nil {
+		return nil, err
+	}
+
+	var stopped []string
+	for _, c := range slices.Backward(required) {
+		s := smug.forConfig(c)
+		if slices.Contains(needed, c.Session) || !s.tmux.SessionExists(c.Session+":") {
+			continue
+		}
+
+		err := s.Stop(c, &Options{}, context)
+		if err != nil {
+			return stopped, fmt.Errorf("stop %s: %w", c.Session, err)
+		}
+		stopped = append(stopped, c.Session)
+	}
+
+	return stopped, nil
+}
+
+// neededSessions returns the sessions required by the running smug sessions
+// other than the given ones.
+func (smug Smug) neededSessions(stopping []*Config, requirements Requirements) ([]string, error) {
+	sessions, err := smug.SmugSessions()
+	if err != nil {
+		return nil, err
+	}
+
+	var needed []string
+	for _, session := range sessions {
+		if slices.ContainsFunc(stopping, func(c *Config) bool { return c.Session == session }) {
+			continue
+		}
+
+		env, err := smug.tmux.ShowEnvironment(session)
+		if err != nil {
+			return nil, err
+		}
+
+		// Sessions started from stdin have no config to read requirements from
+		path, ok := env["SMUG_SESSION_CONFIG_PATH"]
+		if !ok {
+			continue
+		}
+
+		config, err := requirements.Load(path)
+		if err != nil {
+			continue
+		}
+
+		required, err := requirements.resolve(config)
+		if err != nil {
+			return nil, err
+		}
+		for _, c := range required {
+			needed = append(needed, c.Session)
+		}
+	}
+
+	return needed, nil
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testRequirements resolves projects from configs keyed by project name,
+// which is also used as the config path.
+func testRequirements(configs map[string]*Config) Requirements {
+	return Requirements{
+		Find: func(project string) ([]string, error) {
+			if _, ok := configs[project]; !ok {
+				return nil, ConfigNotFoundError{Project: project}
+			}
+			return []string{project}, nil
+		},
+		Load: func(path string) (*Config, error) {
+			return configs[path], nil
+		},
+	}
+}
+
+// runningCommander answers the tmux queries about the running sessions,
+// which are smug sessions started from the config path they map to.
+type runningCommander struct {
+	MockCommander
+	running map[string]string
+}
+
+func (c *runningCommander) Exec(cmd *exec.Cmd) (string, error) {
+	c.Commands = append(c.Commands, strings.Join(cmd.Args, " "))
+
+	_, args := splitTmuxArgs(cmd.Args)
+	switch args[0] {
+	case "list-sessions":
+		var sessions []string
+		for session := range c.running {
+			sessions = append(sessions, session)
+		}
+		slices.Sort(sessions)
+		return strings.Join(sessions, "\n"), nil
+	case "show-environment":
+		session := cmd.Args[len(cmd.Args)-1]
+		return fmt.Sprintf("SMUG_SESSION=%s\nSMUG_SESSION_CONFIG_PATH=%s", session, c.running[session]), nil
+	}
+
+	return "", nil
+}
+
+var requirementsConfigs = map[string]*Config{
+	"web":      {Session: "web", Requires: []string{"api", "postgres"}},
+	"admin":    {Session: "admin", Requires: []string{"postgres"}},
+	"api":      {Session: "api", Requires: []string{"postgres"}},
+	"postgres": {Session: "postgres"},
+	"a":        {Session: "a", Requires: []string{"b"}},
+	"b":        {Session: "b", Requires: []string{"a"}},
+	"broken":   {Session: "broken", Requires: []string{"missing"}},
+}
+
+var resolveRequirementsTestTable = map[string]struct {
+	project  string
+	sessions []string
+	err      string
+}{
+	"dependencies first": {
+		project:  "web",
+		sessions: []string{"postgres", "api"},
+	},
+	"cycle": {
+		project: "a",
+		err:     "dependency cycle: a -> b -> a",
+	},
+	"unknown project": {
+		project: "broken",
+		err:     "broken requires missing: config not found for project missing",
+	},
+}
+
+func TestResolveRequirements(t *testing.T) {
+	for testDescription, params := range resolveRequirementsTestTable {
+		t.Run(testDescription, func(t *testing.T) {
+			required, err := testRequirements(requirementsConfigs).resolve(requirementsConfigs[params.project])
+			if params.err != "" {
+				if err == nil || err.Error() != params.err {
+					t.Errorf("expected error %q, got %v", params.err, err)
+				}
+				return
+			}
+
+			var sessions []string
+			for _, c := range required {
+				sessions = append(sessions, c.Session)
+			}
+			if !reflect.DeepEqual(params.sessions, sessions) {
+				t.Errorf("expected %v, got %v", params.sessions, sessions)
+			}
+		})
+	}
+}
+
+func TestStartRequirements(t *testing.T) {
+	commander := &runningCommander{running: map[string]string{"postgres": "postgres"}}
+	smug := Smug{Tmux{commander, &TmuxOptions{}}, commander}
+
+	err := smug.StartRequirements(requirementsConfigs["web"], testRequirements(requirementsConfigs), Context{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	commands := strings.Join(commander.Commands, "\n")
+	if !strings.Contains(commands, "tmux new -Pd -s api") {
+		t.Errorf("expected api to be started, got %s", commands)
+	}
+	if strings.Contains(commands, "-s postgres") || strings.Contains(commands, "-s web") {
+		t.Errorf("expected only api to be started, got %s", commands)
+	}
+}
+
+func TestStopRequirements(t *testing.T) {
+	// admin still needs postgres
+	commander := &runningCommander{running: map[string]string{
+		"api":      "api",
+		"postgres": "postgres",
+		"admin":    "admin",
+	}}
+	smug := Smug{Tmux{commander, &TmuxOptions{}}, commander}
+
+	stopped, err := smug.StopRequirements(requirementsConfigs["web"], testRequirements(requirementsConfigs), Context{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual([]string{"api"}, stopped) {
+		t.Errorf("expected to stop api only, got %v", stopped)
+	}
+
+	delete(commander.running, "admin")
+	commander.Commands = nil
+
+	stopped, err = smug.StopRequirements(requirementsConfigs["web"], testRequirements(requirementsConfigs), Context{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual([]string{"api", "postgres"}, stopped) {
+		t.Errorf("expected to stop api then postgres, got %v", stopped)
+	}
+}
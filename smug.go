@@ -2,6 +2,7 @@ package main
 
 import (
 	"errors"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
@@ -44,8 +45,8 @@ func (smug Smug) execShellCommands(commands []string, path string) error {
 }
 
 func (smug Smug) setEnvVariables(target string, env map[string]string) error {
-	for key, value := range env {
-		_, err := smug.tmux.SetEnv(target, key, value)
+	for _, key := range slices.Sorted(maps.Keys(env)) {
+		_, err := smug.tmux.SetEnv(target, key, env[key])
 		if err != nil {
 			return err
 		}
@@ -88,19 +89,25 @@ func (smug Smug) Stop(config *Config, options *Options, context Context) error {
 }
 
 func (smug Smug) Start(config *Config, options *Options, context Context) error {
+	_, err := smug.start(config, options, context)
+	return err
+}
+
+// start starts the session of the config, and reports whether it created
+// the session, also when it fails afterwards.
+func (smug Smug) start(config *Config, options *Options, context Context) (created bool, err error) {
 	var sessionName string
-	var err error
 
 	createWindowsInsideCurrSession := options.InsideCurrentSession
 	if createWindowsInsideCurrSession && !context.InsideTmuxSession {
-		return errors.New("cannot use -i flag outside of a tmux session")
+		return created, errors.New("cannot use -i flag outside of a tmux session")
 	}
 
 	sessionName = config.Session
 	if createWindowsInsideCurrSession {
 		sessionName, err = smug.tmux.SessionName()
 		if err != nil {
-			return err
+			return created, err
 		}
 	}
 	sessionName = sessionName + ":"
@@ -113,38 +120,39 @@ func (smug Smug) Start(config *Config, options *Options, context Context) error
 	if !sessionExists && !createWindowsInsideCurrSession {
 		err := smug.execShellCommands(config.BeforeStart, sessionRoot)
 		if err != nil {
-			return err
+			return created, err
 		}
 
 		_, err = smug.tmux.NewSession(config.Session, sessionRoot, defaultWindowName)
 		if err != nil {
-			return err
+			return created, err
 		}
+		created = true
 
 		err = smug.setEnvVariables(config.Session, config.Env)
 		if err != nil {
-			return err
+			return created, err
 		}
 
 		if config.DetachHook != "" {
 			err = smug.tmux.SetHook(config.Session, "client-detached", config.DetachHook)
 			if err != nil {
-				return err
+				return created, err
 			}
 		}
 		if config.AttachHook != "" {
 			err = smug.tmux.SetHook(config.Session, "client-attached", config.AttachHook)
 			if err != nil {
-				return err
+				return created, err
 			}
 		}
 
 	} else if len(windows) == 0 && !createWindowsInsideCurrSession {
 		if options.Detach {
-			return nil
+			return created, nil
 		}
 
-		return smug.switchOrAttach(sessionName, attach, context.InsideTmuxSession)
+		return created, smug.switchOrAttach(sessionName, attach, context.InsideTmuxSession)
 	}
 
 	currentWindowName := ""
@@ -163,14 +171,14 @@ func (smug Smug) Start(config *Config, options *Options, context Context) error
 
 		window, err := smug.tmux.NewWindow(sessionName, w.Name, windowRoot)
 		if err != nil {
-			return err
+			return created, err
 		}
 
 		for _, c := range w.Commands {
 			time.Sleep(time.Millisecond * time.Duration(config.SendKeysTimeout))
 			err := smug.tmux.SendKeys(window, c)
 			if err != nil {
-				return err
+				return created, err
 			}
 		}
 
@@ -182,13 +190,13 @@ func (smug Smug) Start(config *Config, options *Options, context Context) error
 
 			newPane, err := smug.tmux.SplitWindow(window, p.Type, paneRoot)
 			if err != nil {
-				return err
+				return created, err
 			}
 
 			if i%2 == 0 {
 				_, err = smug.tmux.SelectLayout(window, Tiled)
 				if err != nil {
-					return err
+					return created, err
 				}
 
 			}
@@ -197,7 +205,7 @@ func (smug Smug) Start(config *Config, options *Options, context Context) error
 				time.Sleep(time.Millisecond * time.Duration(config.SendKeysTimeout))
 				err = smug.tmux.SendKeys(window+"."+newPane, c)
 				if err != nil {
-					return err
+					return created, err
 				}
 			}
 		}
@@ -209,18 +217,18 @@ func (smug Smug) Start(config *Config, options *Options, context Context) error
 
 		_, err = smug.tmux.SelectLayout(window, layout)
 		if err != nil {
-			return err
+			return created, err
 		}
 	}
 
 	if !options.InsideCurrentSession && !sessionExists {
 		err := smug.tmux.KillWindow(sessionName + defaultWindowName)
 		if err != nil {
-			return err
+			return created, err
 		}
 		err = smug.tmux.RenumberWindows(sessionName)
 		if err != nil {
-			return err
+			return created, err
 		}
 	}
 
@@ -232,54 +240,257 @@ func (smug Smug) Start(config *Config, options *Options, context Context) error
 
 		err := smug.switchOrAttach(sessionName+w, attach, context.InsideTmuxSession)
 		if err != nil && currentWindowName == "" {
-			return err
+			return created, err
 		}
 	}
 
 	if currentWindowName != "" {
-		return smug.tmux.SelectWindow(sessionName + currentWindowName)
+		return created, smug.tmux.SelectWindow(sessionName + currentWindowName)
 	}
-	return nil
+	return created, nil
 }
 
+// GetConfigFromSession returns the config of the session selected with
+// --session or the project argument, or of the current session when running
+// inside tmux.
 func (smug Smug) GetConfigFromSession(options *Options, context Context) (Config, error) {
-	config := Config{}
-
-	tmuxSession, err := smug.tmux.SessionName()
+	session, err := smug.TargetSession(options, context)
 	if err != nil {
 		return Config{}, err
 	}
-	config.Session = tmuxSession
 
-	tmuxWindows, err := smug.tmux.ListWindows(options.Project)
+	return smug.ConfigFromSession(session)
+}
+
+// TargetSession returns the name of the session selected with --session or
+// the project argument, or of the current session when running inside tmux.
+func (smug Smug) TargetSession(options *Options, context Context) (string, error) {
+	if options.Session != "" {
+		return options.Session, nil
+	}
+
+	if options.Project != "" && options.Instance != "" {
+		return options.Project + instanceSeparator + options.Instance, nil
+	}
+
+	if options.Project != "" {
+		return options.Project, nil
+	}
+
+	if !context.InsideTmuxSession {
+		return "", errors.New("a session name is required outside of a tmux session")
+	}
+
+	return smug.tmux.SessionName()
+}
+
+// ConfigFromSession builds a config reproducing the running session.
+func (smug Smug) ConfigFromSession(session string) (Config, error) {
+	config, _, err := smug.captureSession(session)
+
+	// The config is a new one, it doesn't come from the config of the session
+	delete(config.Env, "SMUG_SESSION_CONFIG_PATH")
+	if len(config.Env) == 0 {
+		config.Env = nil
+	}
+
+	return config, err
+}
+
+// captureSession builds a config reproducing the running session, and also
+// returns the tmux panes of every window in the same order as the config.
+func (smug Smug) captureSession(session string) (Config, [][]TmuxPane, error) {
+	config := Config{Session: session}
+	var windowPanes [][]TmuxPane
+
+	env, err := smug.tmux.ShowEnvironment(session)
 	if err != nil {
-		return Config{}, err
+		return Config{}, nil, err
+	}
+	for key := range env {
+		if slices.Contains(sessionEnvBlacklist, key) {
+			delete(env, key)
+		}
+	}
+	if len(env) > 0 {
+		config.Env = env
+	}
+
+	hooks, err := smug.tmux.ShowHooks(session)
+	if err != nil {
+		return Config{}, nil, err
+	}
+	config.AttachHook = hookCommand(hooks["client-attached"])
+	config.DetachHook = hookCommand(hooks["client-detached"])
+
+	tmuxWindows, err := smug.tmux.ListWindows(session)
+	if err != nil {
+		return Config{}, nil, err
 	}
 
 	for _, w := range tmuxWindows {
-		tmuxPanes, err := smug.tmux.ListPanes(options.Project + ":" + w.ID)
+		tmuxPanes, err := smug.tmux.ListPanes(session + ":" + w.ID)
 		if err != nil {
-			return Config{}, err
+			return Config{}, nil, err
 		}
 
-		panes := []Pane{}
-		for _, p := range tmuxPanes[1:] {
-			root := p.Root
-			if root == w.Root {
-				root = ""
+		window := Window{
+			Selected: w.Active,
+			Name:     w.Name,
+			Layout:   w.Layout,
+			Root:     w.Root,
+		}
+
+		// The first pane is created together with the window
+		if len(tmuxPanes) > 0 {
+			window.Root = tmuxPanes[0].Root
+			window.Commands = paneCommands(tmuxPanes[0])
+		}
+
+		for i := 1; i < len(tmuxPanes); i++ {
+			p := tmuxPanes[i]
+
+			splitType := VSplit
+			if p.Top == tmuxPanes[i-1].Top {
+				splitType = HSplit
 			}
-			panes = append(panes, Pane{
-				Root: root,
+
+			window.Panes = append(window.Panes, Pane{
+				Root:     p.Root,
+				Type:     splitType,
+				Commands: paneCommands(p),
 			})
 		}
 
-		config.Windows = append(config.Windows, Window{
-			Name:   w.Name,
-			Layout: w.Layout,
-			Root:   w.Root,
-			Panes:  panes,
-		})
+		config.Windows = append(config.Windows, window)
+		windowPanes = append(windowPanes, tmuxPanes)
+	}
+
+	relativizeRoots(&config)
+
+	return config, windowPanes, nil
+}
+
+// sessionEnvBlacklist lists the variables that shouldn't be saved into the
+// config: the ones smug sets itself and the ones tmux copies from the client
+// environment (update-environment). SMUG_SESSION_CONFIG_PATH is kept so a
+// snapshot still knows the config the session was started from.
+var sessionEnvBlacklist = []string{
+	"SMUG_SESSION",
+	"DISPLAY",
+	"KRB5CCNAME",
+	"SSH_ASKPASS",
+	"SSH_AUTH_SOCK",
+	"SSH_AGENT_PID",
+	"SSH_CONNECTION",
+	"WINDOWID",
+	"XAUTHORITY",
+}
+
+// hookCommand extracts the shell command from a hook set by SetHook.
+func hookCommand(hook string) string {
+	const prefix = `"run-shell \"`
+	const suffix = `\""`
+
+	i := strings.Index(hook, prefix)
+	if i == -1 || !strings.HasSuffix(hook, suffix) {
+		return ""
+	}
+
+	command := hook[i+len(prefix) : len(hook)-len(suffix)]
+	return strings.ReplaceAll(command, `\"`, `"`)
+}
+
+// paneCommands returns the command running in the pane, so it can be started
+// again by sending it to the new pane.
+func paneCommands(p TmuxPane) []string {
+	if p.StartCommand != "" {
+		return []string{p.StartCommand}
+	}
+
+	children, err := childProcesses(p.PID)
+	if err != nil {
+		// No proc filesystem, fall back to the name of the foreground process
+		if p.CurrentCommand == "" || isShell(p.CurrentCommand) {
+			return nil
+		}
+		return []string{p.CurrentCommand}
+	}
+
+	if len(children) == 0 {
+		return nil
+	}
+
+	args, err := processArgs(children[len(children)-1])
+	if err != nil || len(args) == 0 || args[0] == "" {
+		return nil
+	}
+
+	// A copy of the shell itself is a fork that hasn't exec'd yet
+	if shellArgs, err := processArgs(p.PID); err == nil && slices.Equal(args, shellArgs) {
+		return nil
+	}
+
+	return []string{shellQuote(args)}
+}
+
+func isShell(command string) bool {
+	shells := []string{"sh", "bash", "zsh", "fish", "dash", "ksh", "tcsh", "csh", "nu", "elvish", "xonsh"}
+	return slices.Contains(shells, strings.TrimPrefix(command, "-")) ||
+		command == filepath.Base(os.Getenv("SHELL"))
+}
+
+// relativizeRoots sets the session root to the common directory of all
+// windows and panes, and makes window roots relative to the session root and
+// pane roots relative to their window root.
+func relativizeRoots(config *Config) {
+	var roots []string
+	for _, w := range config.Windows {
+		roots = append(roots, w.Root)
+		for _, p := range w.Panes {
+			roots = append(roots, p.Root)
+		}
+	}
+
+	common := commonDir(roots)
+	if common == "" || common == "/" {
+		return
+	}
+	config.Root = common
+
+	for i := range config.Windows {
+		w := &config.Windows[i]
+		windowRoot := w.Root
+		w.Root = relativeRoot(common, windowRoot)
+
+		for j := range w.Panes {
+			w.Panes[j].Root = relativeRoot(windowRoot, w.Panes[j].Root)
+		}
+	}
+}
+
+func relativeRoot(base, path string) string {
+	rel, err := filepath.Rel(base, path)
+	if err != nil || rel == "." {
+		return ""
+	}
+
+	return rel
+}
+
+// commonDir returns the deepest directory containing all the paths.
+func commonDir(paths []string) string {
+	if len(paths) == 0 {
+		return ""
+	}
+
+	common := filepath.Clean(paths[0])
+	for _, p := range paths[1:] {
+		p = filepath.Clean(p)
+		for common != "/" && common != "." && p != common && !strings.HasPrefix(p, common+"/") {
+			common = filepath.Dir(common)
+		}
 	}
 
-	return config, nil
+	return common
 }
@@ -1,9 +1,12 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"os/exec"
+	"path/filepath"
 	"reflect"
+	"strconv"
 	"strings"
 	"testing"
 )
@@ -354,32 +357,58 @@ func TestStartStopSession(t *testing.T) {
 }
 
 func TestPrintCurrentSession(t *testing.T) {
+	procDir = t.TempDir()
+	defer func() { procDir = "/proc" }()
+
+	// Pane 101 runs a shell with htop in the foreground, pane 102 is idle
+	writeFakeProcess(t, 101, 1, "bash", "bash")
+	writeFakeProcess(t, 201, 101, "htop", "htop\x00-d\x005\x00")
+	writeFakeProcess(t, 102, 1, "bash", "bash")
+
 	expectedConfig := Config{
-		Session: "session_name",
+		Session:    "session_name",
+		Root:       "/home/user/project",
+		AttachHook: "echo attached",
+		Env: map[string]string{
+			"FOO": "bar",
+		},
 		Windows: []Window{
 			{
-				Name:   "win1",
-				Root:   "root",
-				Layout: "layout",
+				Selected: true,
+				Name:     "win1",
+				Layout:   "layout",
+				Commands: []string{"htop -d 5"},
 				Panes: []Pane{
 					{
-						Root: "/tmp",
+						Root: "src",
+						Type: HSplit,
+					},
+					{
+						Type:     VSplit,
+						Commands: []string{"tail -f log"},
 					},
 				},
 			},
+			{
+				Name:   "win2",
+				Root:   "docs",
+				Layout: "layout2",
+			},
 		},
 	}
 
 	commander := &MockCommander{[]string{}, []string{
-		"session_name",
-		"id1;win1;layout;root",
-		"root\n/tmp",
+		"-DISPLAY\nFOO=bar\nSMUG_SESSION=session_name\nSMUG_SESSION_CONFIG_PATH=/home/user/.config/smug/project.yml",
+		`client-attached[0] if-shell -F "#{==:#{session_attached},1}" "run-shell \"echo attached\""`,
+		"@1;win1;layout;1;/home/user/project\n@2;win2;layout2;0;/home/user/project/docs",
+		"%1;0;0;101;htop;/home/user/project;\n%2;41;0;102;bash;/home/user/project/src;\n%3;41;13;103;tail;/home/user/project;\"tail -f log\"",
+		"%4;0;0;104;bash;/home/user/project/docs;",
 	}}
 	tmux := Tmux{commander, &TmuxOptions{}}
 
 	smug := Smug{tmux, commander}
 
-	actualConfig, err := smug.GetConfigFromSession(&Options{Project: "test"}, Context{})
+	actualConfig, err := smug.GetConfigFromSession(&Options{Session: "session_name"}, Context{})
 	if err != nil {
 		t.Fatalf("error %v", err)
 	}
@@ -388,3 +417,54 @@ func TestPrintCurrentSession(t *testing.T) {
 		t.Errorf("expected %v, got %v", expectedConfig, actualConfig)
 	}
 }
+
+func writeFakeProcess(t *testing.T, pid, ppid int, comm, cmdline string) {
+	dir := filepath.Join(procDir, strconv.Itoa(pid))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	stat := fmt.Sprintf("%d (%s) S %d 0 0", pid, comm, ppid)
+	if err := os.WriteFile(filepath.Join(dir, "stat"), []byte(stat), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "cmdline"), []byte(cmdline), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrintSessionOutsideTmux(t *testing.T) {
+	commander := &MockCommander{[]string{}, []string{}}
+	tmux := Tmux{commander, &TmuxOptions{}}
+	smug := Smug{tmux, commander}
+
+	_, err := smug.GetConfigFromSession(&Options{}, Context{})
+	if err == nil {
+		t.Fatal("expected error when no session is given outside of tmux")
+	}
+
+	if len(commander.Commands) != 0 {
+		t.Errorf("expected no commands, got %v", commander.Commands)
+	}
+}
+
+func TestTargetSessionInstance(t *testing.T) {
+	smug := Smug{Tmux{&MockCommander{}, &TmuxOptions{}}, &MockCommander{}}
+
+	cases := []struct {
+		options  Options
+		expected string
+	}{
+		{Options{Project: "blog"}, "blog"},
+		{Options{Project: "blog", Instance: "review"}, "blog@review"},
+		{Options{Project: "blog@review"}, "blog@review"},
+		{Options{Project: "blog", Session: "notes", Instance: "review"}, "notes"},
+	}
+
+	for _, c := range cases {
+		session, err := smug.TargetSession(&c.options, Context{})
+		if err != nil || session != c.expected {
+			t.Errorf("expected %s, got %s (%v)", c.expected, session, err)
+		}
+	}
+}
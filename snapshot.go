@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const snapshotConfigFile = "session.yml"
+
+// StateDir returns the directory where smug keeps saved sessions and other
+// state, $XDG_STATE_HOME/smug or ~/.local/state/smug.
+func StateDir() string {
+	if dir := os.Getenv("XDG_STATE_HOME"); dir != "" {
+		return filepath.Join(dir, "smug")
+	}
+
+	return filepath.Join(ExpandPath("~/"), ".local/state/smug")
+}
+
+// SessionsDir returns the directory of the sessions saved with `smug save`.
+func SessionsDir() string {
+	return filepath.Join(StateDir(), "sessions")
+}
+
+// scrollbackFile returns the name of the file holding the scrollback of a
+// pane. Pane 0 is the one the window was created with, the next ones are the
+// window's panes in order.
+func scrollbackFile(window, pane int) string {
+	return fmt.Sprintf("%d.%d.txt", window, pane)
+}
+
+// SaveSession writes a snapshot of the running session into dir: the config
+// reproducing it, and the last scrollback lines of every pane if scrollback
+// is greater than 0.
+func (smug Smug) SaveSession(session string, dir string, scrollback int) error {
+	config, windowPanes, err := smug.captureSession(session)
+	if err != nil {
+		return err
+	}
+
+	d, err := yaml.Marshal(&config)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(dir), 0o750); err != nil {
+		return err
+	}
+
+	// The snapshot is written next to the previous one and then swapped
+	// with it, a failed save leaves the previous snapshot untouched
+	tmp, err := os.MkdirTemp(filepath.Dir(dir), "."+filepath.Base(dir)+"-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := os.Chmod(tmp, 0o750); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(filepath.Join(tmp, snapshotConfigFile), d, 0o600); err != nil {
+		return err
+	}
+
+	if scrollback > 0 {
+		for i, panes := range windowPanes {
+			for j, p := range panes {
+				contents, err := smug.tmux.CapturePane(p.ID, scrollback)
+				if err != nil {
+					return err
+				}
+
+				contents = strings.TrimRight(contents, "\n") + "\n"
+				err = os.WriteFile(filepath.Join(tmp, scrollbackFile(i, j)), []byte(contents), 0o600)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return replaceDir(tmp, dir)
+}
+
+// replaceDir moves src to dst, replacing the directory already there.
+func replaceDir(src string, dst string) error {
+	old := src + ".old"
+	if err := os.Rename(dst, old); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	if err := os.Rename(src, dst); err != nil {
+		os.Rename(old, dst)
+		return err
+	}
+
+	return os.RemoveAll(old)
+}
+
+// LoadSnapshot reads a session snapshot saved by SaveSession. Panes with a
+// saved scrollback print it before running their commands.
+func LoadSnapshot(dir string, tmuxOpts *TmuxOptions) (*Config, error) {
+	path := filepath.Join(dir, snapshotConfigFile)
+	f, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	// Snapshots hold the commands as they were running, so unlike regular
+	// configs they are not expanded
+	c := Config{}
+	if err := yaml.Unmarshal(f, &c); err != nil {
+		return nil, err
+	}
+
+	if c.Env == nil {
+		c.Env = make(map[string]string)
+	}
+	// A session started from a config keeps it, rather than the snapshot
+	// removed with older autosaves
+	configPath := path
+	if original, ok := c.Env["SMUG_SESSION_CONFIG_PATH"]; ok {
+		configPath = original
+	}
+	addDefaultEnvs(&c, configPath)
+	setTmuxOptions(tmuxOpts, c)
+
+	for i := range c.Windows {
+		w := &c.Windows[i]
+		w.Commands = withScrollback(dir, i, 0, w.Commands)
+		for j := range w.Panes {
+			w.Panes[j].Commands = withScrollback(dir, i, j+1, w.Panes[j].Commands)
+		}
+	}
+
+	return &c, nil
+}
+
+func withScrollback(dir string, window, pane int, commands []string) []string {
+	file := filepath.Join(dir, scrollbackFile(window, pane))
+	if _, err := os.Stat(file); err != nil {
+		return commands
+	}
+
+	return append([]string{"clear; cat " + shellQuote([]string{file})}, commands...)
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSaveAndLoadSnapshot(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "demo")
+
+	commander := &MockCommander{[]string{}, []string{
+		"SMUG_SESSION=demo\nSMUG_SESSION_CONFIG_PATH=/home/user/.config/smug/demo.yml",
+		"",
+		"@1;win1;layout;1;/home/user/project",
+		"%1;0;0;101;bash;/home/user/project;\n%2;41;0;102;bash;/home/user/project;\"tail -f log\"",
+		"$ make\nok",
+		"",
+	}}
+	tmux := Tmux{commander, &TmuxOptions{}}
+	smug := Smug{tmux, commander}
+
+	err := smug.SaveSession("demo", dir, 500)
+	if err != nil {
+		t.Fatalf("error %v", err)
+	}
+
+	captures := []string{
+		"tmux capture-pane -p -e -J -S -500 -t %1",
+		"tmux capture-pane -p -e -J -S -500 -t %2",
+	}
+	if !reflect.DeepEqual(captures, commander.Commands[len(commander.Commands)-2:]) {
+		t.Errorf("expected %v, got %v", captures, commander.Commands)
+	}
+
+	scrollback, err := os.ReadFile(filepath.Join(dir, "0.0.txt"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(scrollback) != "$ make\nok\n" {
+		t.Errorf("expected scrollback %q, got %q", "$ make\nok\n", scrollback)
+	}
+
+	config, err := LoadSnapshot(dir, &TmuxOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expectedWindows := []Window{
+		{
+			Selected:    true,
+			Name:        "win1",
+			Layout:      "layout",
+			BeforeStart: []string{},
+			Commands:    []string{"clear; cat " + filepath.Join(dir, "0.0.txt")},
+			Panes: []Pane{
+				{
+					Type: HSplit,
+					Commands: []string{
+						"clear; cat " + filepath.Join(dir, "0.1.txt"),
+						"tail -f log",
+					},
+				},
+			},
+		},
+	}
+
+	if config.Session != "demo" || config.Root != "/home/user/project" {
+		t.Errorf("unexpected session %q with root %q", config.Session, config.Root)
+	}
+
+	if path := config.Env["SMUG_SESSION_CONFIG_PATH"]; path != "/home/user/.config/smug/demo.yml" {
+		t.Errorf("expected the config path of the saved session, got %q", path)
+	}
+
+	if !reflect.DeepEqual(expectedWindows, config.Windows) {
+		t.Errorf("expected %v, got %v", expectedWindows, config.Windows)
+	}
+}
+
+func TestSaveSnapshotReplacesPrevious(t *testing.T) {
+	parent := t.TempDir()
+	dir := filepath.Join(parent, "demo")
+
+	if err := os.MkdirAll(dir, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "3.0.txt"), []byte("stale"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	commander := &MockCommander{[]string{}, []string{
+		"",
+		"",
+		"@1;win1;layout;1;/home/user/project",
+		"%1;0;0;101;bash;/home/user/project;",
+	}}
+	smug := Smug{Tmux{commander, &TmuxOptions{}}, commander}
+
+	if err := smug.SaveSession("demo", dir, 0); err != nil {
+		t.Fatalf("error %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, "3.0.txt")); !os.IsNotExist(err) {
+		t.Errorf("expected the previous snapshot to be replaced, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, snapshotConfigFile)); err != nil {
+		t.Error(err)
+	}
+
+	entries, err := os.ReadDir(parent)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the snapshot to be left, got %v", entries)
+	}
+}
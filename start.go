@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"sync"
+)
+
+// maxParallelStarts bounds the number of projects started at the same time.
+const maxParallelStarts = 4
+
+// ProjectStart is a project to start, with the configs of its sessions in
+// start order: a single config, or the members of a group.
+type ProjectStart struct {
+	Name    string
+	Configs []*Config
+	// Session is the session attached when the project is started alone
+	Session string
+}
+
+// StartProjects starts the sessions of the projects detached. The projects
+// are started concurrently, at most parallel at a time, and the configs of a
+// project one after the other. A project that fails to start is rolled back,
+// without stopping the other projects. The error of each project is returned
+// at its index, nil when it started.
+func (smug Smug) StartProjects(projects []ProjectStart, options *Options, context Context, parallel int) []error {
+	if parallel <= 0 {
+		parallel = maxParallelStarts
+	}
+
+	detached := *options
+	detached.Detach = true
+
+	errs := make([]error, len(projects))
+	slots := make(chan struct{}, parallel)
+
+	var wg sync.WaitGroup
+	for i, project := range projects {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			slots <- struct{}{}
+			defer func() { <-slots }()
+
+			if err := smug.StartConfigs(project.Configs, &detached, context); err != nil {
+				errs[i] = fmt.Errorf("%s: %w", project.Name, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	return errs
+}
+
+// StartConfigs starts the configs one after the other. When one fails, the
+// sessions created by this call are stopped, the last one first. Sessions
+// that were already running, that another smug created meanwhile, or whose
+// before_start failed, are left alone.
+func (smug Smug) StartConfigs(configs []*Config, options *Options, context Context) error {
+	var created []*Config
+	for _, config := range configs {
+		ok, err := smug.forConfig(config).start(config, options, context)
+		if ok {
+			created = append(created, config)
+		}
+		if err == nil {
+			continue
+		}
+
+		for _, c := range slices.Backward(created) {
+			smug.forConfig(c).Stop(c, &Options{}, context)
+		}
+		return err
+	}
+
+	return nil
+}
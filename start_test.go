@@ -0,0 +1,148 @@
+package main
+
+import (
+	"errors"
+	"os/exec"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// failingCommander fails to create the given session. It is safe for
+// concurrent use.
+type failingCommander struct {
+	mu       sync.Mutex
+	commands []string
+	// fail is the session that can't be created
+	fail string
+	// failCommand is the prefix of another command failing
+	failCommand string
+	// running are the sessions already running
+	running []string
+	// outputs are the outputs of the commands starting with their keys
+	outputs map[string]string
+}
+
+func (c *failingCommander) Exec(cmd *exec.Cmd) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	command := strings.Join(cmd.Args, " ")
+	c.commands = append(c.commands, command)
+
+	if strings.HasPrefix(command, "tmux new -Pd -s "+c.fail+" ") {
+		return "", errors.New("duplicate session")
+	}
+	if c.failCommand != "" && strings.HasPrefix(command, c.failCommand) {
+		return "", errors.New("command failed")
+	}
+	if strings.HasPrefix(command, "tmux list-sessions") {
+		return strings.Join(c.running, "\n"), nil
+	}
+	for prefix, output := range c.outputs {
+		if strings.HasPrefix(command, prefix) {
+			return output, nil
+		}
+	}
+
+	return "", nil
+}
+
+func (c *failingCommander) ExecSilently(cmd *exec.Cmd) error {
+	_, err := c.Exec(cmd)
+	return err
+}
+
+func (c *failingCommander) ran(prefix string) bool {
+	for _, command := range c.commands {
+		if strings.HasPrefix(command, prefix) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestStartProjects(t *testing.T) {
+	commander := &failingCommander{fail: "web", failCommand: "tmux neww -Pd -t worker: -c  -F #{window_id} -n logs"}
+	smug := Smug{Tmux{commander, &TmuxOptions{}}, commander}
+
+	projects := []ProjectStart{
+		{Name: "api", Configs: []*Config{{Session: "api", Windows: []Window{{Name: "code"}}}}},
+		{Name: "web", Configs: []*Config{{Session: "web", Windows: []Window{{Name: "code"}}}}},
+		{Name: "backend", Configs: []*Config{
+			{Session: "db", Windows: []Window{{Name: "psql"}}},
+			{Session: "worker", Windows: []Window{{Name: "logs"}}},
+		}},
+	}
+
+	errs := smug.StartProjects(projects, &Options{}, Context{}, 2)
+	if len(errs) != 3 || errs[0] != nil || errs[1] == nil || errs[1].Error() != "web: duplicate session" || errs[2] == nil {
+		t.Errorf("expected the errors of web and backend, got %v", errs)
+	}
+
+	for _, session := range []string{"api", "db", "worker"} {
+		if !commander.ran("tmux new -Pd -s " + session + " ") {
+			t.Errorf("expected %s to be started, got %v", session, commander.commands)
+		}
+	}
+
+	// web was created by someone else, it's not ours to kill
+	if commander.ran("tmux kill-session -t web") {
+		t.Errorf("expected web to be left alone, got %v", commander.commands)
+	}
+
+	// The whole group is rolled back, in reverse order
+	var killed []string
+	for _, command := range commander.commands {
+		if session, ok := strings.CutPrefix(command, "tmux kill-session -t "); ok {
+			killed = append(killed, session)
+		}
+	}
+	if !slices.Equal(killed, []string{"worker", "db"}) {
+		t.Errorf("expected worker then db to be rolled back, got %v", killed)
+	}
+
+	if commander.ran("tmux attach") || commander.ran("tmux switch-client") {
+		t.Errorf("expected the sessions to be started detached, got %v", commander.commands)
+	}
+}
+
+func TestStartConfigsKeepsRunningSessions(t *testing.T) {
+	// api already runs, so the window is added to it
+	commander := &failingCommander{failCommand: "tmux neww", running: []string{"api"}}
+	smug := Smug{Tmux{commander, &TmuxOptions{}}, commander}
+
+	config := &Config{Session: "api", Windows: []Window{{Name: "code"}}}
+	err := smug.StartConfigs([]*Config{config}, &Options{Windows: []string{"code"}}, Context{})
+	if err == nil {
+		t.Fatal("expected the error of the window")
+	}
+
+	if commander.ran("tmux kill-session") || commander.ran("tmux kill-window") {
+		t.Errorf("expected the running session to be left alone, got %v", commander.commands)
+	}
+}
+
+func TestStartConfigsSkipsFailedBeforeStart(t *testing.T) {
+	commander := &failingCommander{failCommand: "/bin/sh -c false"}
+	smug := Smug{Tmux{commander, &TmuxOptions{}}, commander}
+
+	configs := []*Config{
+		{Session: "cache", Stop: []string{"echo cache"}, Windows: []Window{{Name: "redis"}}},
+		{Session: "bad", BeforeStart: []string{"false"}, Stop: []string{"echo bad"}, Windows: []Window{{Name: "code"}}},
+		{Session: "demo", Windows: []Window{{Name: "code"}}},
+	}
+	if err := smug.StartConfigs(configs, &Options{Detach: true}, Context{}); err == nil {
+		t.Fatal("expected the error of before_start")
+	}
+
+	// bad has no session, only cache is rolled back
+	if commander.ran("tmux new -Pd -s bad ") || commander.ran("/bin/sh -c echo bad") || commander.ran("tmux kill-session -t bad") {
+		t.Errorf("expected bad to be left alone, got %v", commander.commands)
+	}
+	if !commander.ran("/bin/sh -c echo cache") || !commander.ran("tmux kill-session -t cache") {
+		t.Errorf("expected cache to be rolled back, got %v", commander.commands)
+	}
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"os"
 	"os/exec"
+	"strconv"
 	"strings"
 	"fmt"
 )
@@ -19,13 +20,13 @@ const (
 
 type TmuxOptions struct {
 	// Default socket name
-	SocketName string `yaml:"socket_name"`
+	SocketName string `yaml:"socket_name" json:"socket_name"`
 
 	// Default socket path, overrides SocketName
-	SocketPath string `yaml:"socket_path"`
+	SocketPath string `yaml:"socket_path" json:"socket_path"`
 
 	// tmux config file
-	ConfigFile string `yaml:"config_file"`
+	ConfigFile string `yaml:"config_file" json:"config_file"`
 }
 
 type Tmux struct {
@@ -38,10 +39,17 @@ type TmuxWindow struct {
 	Name   string
 	Layout string
 	Root   string
+	Active bool
 }
 
 type TmuxPane struct {
-	Root string
+	ID             string
+	Root           string
+	Left           int
+	Top            int
+	PID            int
+	CurrentCommand string
+	StartCommand   string
 }
 
 func (tmux Tmux) cmd(args ...string) *exec.Cmd {
@@ -173,10 +181,24 @@ func (tmux Tmux) SessionName() (string, error) {
 	return sessionName, nil
 }
 
+func (tmux Tmux) ListSessions() ([]string, error) {
+	cmd := tmux.cmd("list-sessions", "-F", "#{session_name}")
+	out, err := tmux.commander.Exec(cmd)
+	if err != nil {
+		return nil, err
+	}
+
+	if out == "" {
+		return nil, nil
+	}
+
+	return strings.Split(out, "\n"), nil
+}
+
 func (tmux Tmux) ListWindows(target string) ([]TmuxWindow, error) {
 	var windows []TmuxWindow
 
-	cmd := tmux.cmd("list-windows", "-F", "#{window_id};#{window_name};#{window_layout};#{pane_current_path}", "-t", target)
+	cmd := tmux.cmd("list-windows", "-F", "#{window_id};#{window_name};#{window_layout};#{window_active};#{pane_current_path}", "-t", target)
 	out, err := tmux.commander.Exec(cmd)
 	if err != nil {
 		return windows, err
@@ -185,12 +207,16 @@ func (tmux Tmux) ListWindows(target string) ([]TmuxWindow, error) {
 	windowsList := strings.Split(out, "\n")
 
 	for _, w := range windowsList {
-		windowInfo := strings.Split(w, ";")
+		windowInfo := strings.SplitN(w, ";", 5)
+		if len(windowInfo) < 5 {
+			continue
+		}
 		window := TmuxWindow{
 			ID:     windowInfo[0],
 			Name:   windowInfo[1],
 			Layout: windowInfo[2],
-			Root:   windowInfo[3],
+			Active: windowInfo[3] == "1",
+			Root:   windowInfo[4],
 		}
 		windows = append(windows, window)
 	}
@@ -201,7 +227,7 @@ func (tmux Tmux) ListWindows(target string) ([]TmuxWindow, error) {
 func (tmux Tmux) ListPanes(target string) ([]TmuxPane, error) {
 	var panes []TmuxPane
 
-	cmd := tmux.cmd("list-panes", "-F", "#{pane_current_path}", "-t", target)
+	cmd := tmux.cmd("list-panes", "-F", "#{pane_id};#{pane_left};#{pane_top};#{pane_pid};#{pane_current_command};#{pane_current_path};#{pane_start_command}", "-t", target)
 
 	out, err := tmux.commander.Exec(cmd)
 	if err != nil {
@@ -211,9 +237,21 @@ func (tmux Tmux) ListPanes(target string) ([]TmuxPane, error) {
 	panesList := strings.Split(out, "\n")
 
 	for _, p := range panesList {
-		paneInfo := strings.Split(p, ";")
+		paneInfo := strings.SplitN(p, ";", 7)
+		if len(paneInfo) < 7 {
+			continue
+		}
+		left, _ := strconv.Atoi(paneInfo[1])
+		top, _ := strconv.Atoi(paneInfo[2])
+		pid, _ := strconv.Atoi(paneInfo[3])
 		pane := TmuxPane{
-			Root: paneInfo[0],
+			ID:             paneInfo[0],
+			Left:           left,
+			Top:            top,
+			PID:            pid,
+			CurrentCommand: paneInfo[4],
+			Root:           paneInfo[5],
+			StartCommand:   unquoteTmux(paneInfo[6]),
 		}
 
 		panes = append(panes, pane)
@@ -222,6 +260,80 @@ func (tmux Tmux) ListPanes(target string) ([]TmuxPane, error) {
 	return panes, nil
 }
 
+// CapturePane returns the visible contents of the pane together with up to
+// lines lines of its history, including escape sequences for colors.
+func (tmux Tmux) CapturePane(target string, lines int) (string, error) {
+	cmd := tmux.cmd("capture-pane", "-p", "-e", "-J", "-S", "-"+strconv.Itoa(lines), "-t", target)
+	return tmux.commander.Exec(cmd)
+}
+
+// ShowEnvironment returns the variables set in the session environment.
+// Variables marked as removed from the session are skipped.
+func (tmux Tmux) ShowEnvironment(target string) (map[string]string, error) {
+	env := make(map[string]string)
+
+	cmd := tmux.cmd("show-environment", "-t", target)
+	out, err := tmux.commander.Exec(cmd)
+	if err != nil {
+		return env, err
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		key, value, ok := strings.Cut(line, "=")
+		if !ok || strings.HasPrefix(key, "-") {
+			continue
+		}
+		env[key] = value
+	}
+
+	return env, nil
+}
+
+// ShowHooks returns the commands of the hooks set on the session, by the
+// hook event name.
+func (tmux Tmux) ShowHooks(target string) (map[string]string, error) {
+	hooks := make(map[string]string)
+
+	cmd := tmux.cmd("show-hooks", "-t", target)
+	out, err := tmux.commander.Exec(cmd)
+	if err != nil {
+		return hooks, err
+	}
+
+	for _, line := range strings.Split(out, "\n") {
+		name, command, ok := strings.Cut(line, " ")
+		if !ok {
+			continue
+		}
+		// Array hooks are printed as event[index]
+		if i := strings.Index(name, "["); i != -1 {
+			name = name[:i]
+		}
+		hooks[name] = command
+	}
+
+	return hooks, nil
+}
+
+// unquoteTmux reverses the quoting tmux applies to a format value holding a
+// command with spaces.
+func unquoteTmux(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+
+	var b strings.Builder
+	s = s[1 : len(s)-1]
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+
+	return b.String()
+}
+
 func (tmux Tmux) SetHook(target string, hookEvent string, command string) error {
 	// for client-detached 0 means last detached client
 	// for client-attached 1 means first attached client
@@ -243,3 +355,30 @@ func (tmux Tmux) SetHook(target string, hookEvent string, command string) error
 	cmd := tmux.cmd("set-hook", "-t", target, hookEvent, hookCondition)
 	return tmux.commander.ExecSilently(cmd)
 }
+
+// MenuItem is an entry of a tmux menu. An item without a name is a
+// separator, and an item whose name starts with "-" is disabled.
+type MenuItem struct {
+	Name    string
+	Key     string
+	Command string
+}
+
+func (tmux Tmux) DisplayMenu(title string, items []MenuItem) error {
+	args := []string{"display-menu", "-T", title, "-x", "C", "-y", "C"}
+	for _, item := range items {
+		if item.Name == "" {
+			args = append(args, "")
+			continue
+		}
+		args = append(args, item.Name, item.Key, item.Command)
+	}
+
+	cmd := tmux.cmd(args...)
+	return tmux.commander.ExecSilently(cmd)
+}
+
+func (tmux Tmux) DisplayPopup(command string) error {
+	cmd := tmux.cmd("display-popup", "-E", "-w", "80%", "-h", "80%", command)
+	return tmux.commander.ExecSilently(cmd)
+}
@@ -2,14 +2,29 @@ package main
 
 import (
 	"fmt"
+	"io"
+	"maps"
+	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"slices"
 	"strings"
+	"text/tabwriter"
 )
 
+// Worktree is a git worktree, with the branch checked out in it.
 type Worktree struct {
 	Path   string
 	Branch string
+	// Head is the commit checked out, the one addressing detached worktrees
+	Head string
+	// Bare is set for the bare repository of a bare layout, which has no
+	// checkout
+	Bare   bool
+	Locked bool
+	// Prunable is set when the directory of the worktree is gone
+	Prunable bool
 }
 
 // GitWorktrees lists the git worktrees of the repository containing root.
@@ -29,12 +44,23 @@ func parseWorktrees(out string) []Worktree {
 	var current Worktree
 
 	for line := range strings.SplitSeq(out, "\n") {
-		switch {
-		case strings.HasPrefix(line, "worktree "):
-			current = Worktree{Path: strings.TrimPrefix(line, "worktree ")}
-		case strings.HasPrefix(line, "branch "):
-			current.Branch = strings.TrimPrefix(strings.TrimPrefix(line, "branch "), "refs/heads/")
-		case line == "":
+		// locked and prunable may be followed by a reason
+		key, value, _ := strings.Cut(line, " ")
+
+		switch key {
+		case "worktree":
+			current = Worktree{Path: value}
+		case "HEAD":
+			current.Head = value
+		case "branch":
+			current.Branch = strings.TrimPrefix(value, "refs/heads/")
+		case "bare":
+			current.Bare = true
+		case "locked":
+			current.Locked = true
+		case "prunable":
+			current.Prunable = true
+		case "":
 			if current.Path != "" {
 				worktrees = append(worktrees, current)
 				current = Worktree{}
@@ -49,14 +75,453 @@ func parseWorktrees(out string) []Worktree {
 	return worktrees
 }
 
-// FindWorktree returns the path of the worktree matching name, by either its
-// branch name or its directory basename.
-func FindWorktree(worktrees []Worktree, name string) (string, error) {
+// checkedOut reports whether the worktree has files to start a session in.
+func (wt Worktree) checkedOut() bool {
+	return !wt.Bare && !wt.Prunable
+}
+
+// name is the branch of the worktree, or the short commit of a detached one.
+func (wt Worktree) name() string {
+	if wt.Branch != "" {
+		return wt.Branch
+	}
+
+	return shortCommit(wt.Head)
+}
+
+func shortCommit(head string) string {
+	if len(head) > 7 {
+		return head[:7]
+	}
+
+	return head
+}
+
+type WorktreeNotFoundError struct {
+	Name string
+}
+
+func (e WorktreeNotFoundError) Error() string {
+	return fmt.Sprintf("worktree %q not found", e.Name)
+}
+
+// minCommitPrefix is the shortest commit prefix addressing a detached
+// worktree.
+const minCommitPrefix = 4
+
+// findExactWorktree returns the worktree whose path, branch or directory
+// basename is name, or the detached worktree whose commit starts with name.
+func findExactWorktree(worktrees []Worktree, name string) (Worktree, bool) {
+	for _, wt := range worktrees {
+		switch {
+		case !wt.checkedOut():
+			continue
+		case wt.Path == name, wt.Branch == name, filepath.Base(wt.Path) == name:
+			return wt, true
+		case wt.Branch == "" && len(name) >= minCommitPrefix && strings.HasPrefix(wt.Head, name):
+			return wt, true
+		}
+	}
+
+	return Worktree{}, false
+}
+
+// FindWorktree returns the worktree matching name: by its path, its branch
+// name, its directory basename or the commit of a detached worktree, then by a unique
+// prefix of the branch or the basename, then by a unique fuzzy match. Bare
+// and prunable worktrees are never matched.
+func FindWorktree(worktrees []Worktree, name string) (Worktree, error) {
+	if wt, ok := findExactWorktree(worktrees, name); ok {
+		return wt, nil
+	}
+
+	var prefixed []Worktree
+	for _, wt := range worktrees {
+		if wt.checkedOut() && (strings.HasPrefix(wt.Branch, name) || strings.HasPrefix(filepath.Base(wt.Path), name)) {
+			prefixed = append(prefixed, wt)
+		}
+	}
+	if len(prefixed) > 0 {
+		return uniqueWorktree(prefixed, name)
+	}
+
+	type match struct {
+		wt    Worktree
+		score int
+	}
+
+	var matches []match
+	for _, wt := range worktrees {
+		if !wt.checkedOut() {
+			continue
+		}
+
+		score, ok := fuzzyScore(name, wt.name())
+		if baseScore, baseOk := fuzzyScore(name, filepath.Base(wt.Path)); baseOk && (!ok || baseScore > score) {
+			score, ok = baseScore, true
+		}
+		if ok {
+			matches = append(matches, match{wt, score})
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b match) int { return b.score - a.score })
+
+	fuzzy := make([]Worktree, len(matches))
+	for i, m := range matches {
+		fuzzy[i] = m.wt
+	}
+
+	return uniqueWorktree(fuzzy, name)
+}
+
+// uniqueWorktree returns the only worktree matching name, and an error
+// naming the candidates when there are several.
+func uniqueWorktree(matches []Worktree, name string) (Worktree, error) {
+	switch len(matches) {
+	case 0:
+		return Worktree{}, WorktreeNotFoundError{name}
+	case 1:
+		return matches[0], nil
+	}
+
+	names := make([]string, len(matches))
+	for i, wt := range matches {
+		names[i] = wt.name()
+	}
+
+	return Worktree{}, fmt.Errorf("worktree %q is ambiguous, it matches %s", name, strings.Join(names, ", "))
+}
+
+// sessionNameReplacer replaces the characters tmux doesn't allow in session
+// names.
+var sessionNameReplacer = strings.NewReplacer(".", "_", ":", "_", " ", "_", "\t", "_")
+
+// WorktreeSession returns the session of a worktree of the project whose
+// session is session, e.g. blog/feature-x, so several worktrees of a project
+// can run side by side. Detached worktrees are named after their directory.
+func WorktreeSession(session string, wt Worktree) string {
+	name := wt.Branch
+	if name == "" {
+		name = filepath.Base(wt.Path)
+	}
+
+	return session + "/" + sessionNameReplacer.Replace(name)
+}
+
+// setWorktree roots the config in the worktree, in a session named after it.
+func setWorktree(config *Config, wt Worktree) {
+	config.Root = wt.Path
+	config.Session = WorktreeSession(config.Session, wt)
+	config.Env["SMUG_SESSION"] = config.Session
+	config.Env["SMUG_WORKTREE"] = wt.Path
+	config.Env["SMUG_BRANCH"] = wt.Branch
+}
+
+// AllWorktrees is the branch glob of --all-worktrees without a value.
+const AllWorktrees = "*"
+
+// WorktreeConfigs returns a copy of the config for each worktree of its
+// repository whose branch matches the glob pattern, rooted in the worktree.
+// Detached worktrees match by their short commit, and AllWorktrees matches
+// every worktree, whatever the slashes in the branch.
+func WorktreeConfigs(config *Config, pattern string, commander Commander) ([]*Config, error) {
+	root, err := worktreeRoot(config)
+	if err != nil {
+		return nil, err
+	}
+
+	worktrees, err := GitWorktrees(commander, root)
+	if err != nil {
+		return nil, err
+	}
+
+	var configs []*Config
+	for _, wt := range worktrees {
+		if !wt.checkedOut() {
+			continue
+		}
+
+		matched, err := path.Match(pattern, wt.name())
+		if err != nil {
+			return nil, fmt.Errorf("invalid branch pattern %q: %w", pattern, err)
+		}
+		if !matched && pattern != AllWorktrees {
+			continue
+		}
+
+		c := *config
+		c.Env = maps.Clone(config.Env)
+		setWorktree(&c, wt)
+		configs = append(configs, &c)
+	}
+
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("no worktree of %s matches %q", root, pattern)
+	}
+
+	return configs, nil
+}
+
+// DefaultWorktreePath is where --create adds worktrees when the config sets
+// no worktree_path: next to the main worktree.
+const DefaultWorktreePath = "../{name}-{branch}"
+
+// DefaultBareWorktreePath is the default worktree_path of bare layouts, where
+// the worktrees sit next to the bare repository.
+const DefaultBareWorktreePath = "../{branch}"
+
+// WorktreePath returns the path of a new worktree for branch, from a pattern
+// where {repo} is the path of the main worktree, or of the bare repository,
+// {name} its directory name without .git and {branch} the branch, with
+// slashes replaced. Relative patterns are relative to the main worktree.
+func WorktreePath(pattern string, main Worktree, branch string) string {
+	switch {
+	case pattern != "":
+	case main.Bare:
+		pattern = DefaultBareWorktreePath
+	default:
+		pattern = DefaultWorktreePath
+	}
+
+	path := strings.NewReplacer(
+		"{repo}", main.Path,
+		"{name}", strings.TrimSuffix(filepath.Base(main.Path), ".git"),
+		"{branch}", strings.ReplaceAll(branch, "/", "-"),
+	).Replace(ExpandPath(pattern))
+
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(main.Path, path)
+	}
+
+	return filepath.Clean(path)
+}
+
+// AddWorktree adds a worktree of the repository at path, checking out
+// branch. A missing branch is created from the from revision, or tracks the
+// remote branch of the same name, or is created from HEAD.
+func AddWorktree(commander Commander, repo string, path string, branch string, from string) error {
+	verify := exec.Command("git", "-C", repo, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	_, err := commander.Exec(verify)
+	exists := err == nil
+
+	var remote string
+	if !exists && from == "" {
+		remote, err = remoteBranch(commander, repo, branch)
+		if err != nil {
+			return err
+		}
+	}
+
+	args := []string{"-C", repo, "worktree", "add"}
+	switch {
+	case exists && from != "":
+		return fmt.Errorf("branch %q already exists, --from only applies to new branches", branch)
+	case exists:
+		args = append(args, path, branch)
+	case remote != "":
+		args = append(args, "--track", "-b", branch, path, remote)
+	default:
+		args = append(args, "-b", branch, path)
+		if from != "" {
+			args = append(args, from)
+		}
+	}
+
+	_, err = commander.Exec(exec.Command("git", args...))
+	return err
+}
+
+// remoteBranch returns the remote tracking branch named after branch, as
+// origin/branch, or "" when no remote or several of them have it.
+func remoteBranch(commander Commander, repo string, branch string) (string, error) {
+	cmd := exec.Command("git", "-C", repo, "for-each-ref", "--format=%(refname:short)", "refs/remotes/*/"+branch)
+	out, err := commander.Exec(cmd)
+	if err != nil {
+		return "", err
+	}
+
+	refs := strings.Fields(out)
+	if len(refs) != 1 {
+		return "", nil
+	}
+
+	return refs[0], nil
+}
+
+// RemoveWorktree removes the worktree at path, and its branch with
+// deleteBranch. Unless force, a worktree with uncommitted changes or with
+// commits found on no other branch or remote is kept.
+func RemoveWorktree(commander Commander, path string, deleteBranch bool, force bool) error {
+	worktrees, err := GitWorktrees(commander, path)
+	if err != nil {
+		return err
+	}
+
+	i := slices.IndexFunc(worktrees, func(wt Worktree) bool { return wt.Path == path })
+	switch {
+	case i == -1:
+		return WorktreeNotFoundError{path}
+	case i == 0:
+		return fmt.Errorf("%s is the main worktree, it can't be removed", path)
+	}
+	wt := worktrees[i]
+
+	if deleteBranch && wt.Branch == "" {
+		return fmt.Errorf("worktree %s is detached, there is no branch to delete", path)
+	}
+
+	if wt.Locked && !force {
+		return fmt.Errorf("worktree %s is locked, use --force to remove it anyway", path)
+	}
+
+	if !force {
+		status, err := commander.Exec(exec.Command("git", "-C", path, "status", "--porcelain"))
+		if err != nil {
+			return err
+		}
+		if status != "" {
+			return fmt.Errorf("worktree %s has uncommitted changes, use --force to remove it anyway", path)
+		}
+
+		// Commits reachable from no other branch and no remote would be lost
+		args := []string{"-C", path, "rev-list", "--count", "HEAD", "--not"}
+		if wt.Branch != "" {
+			args = append(args, "--exclude="+wt.Branch)
+		}
+		args = append(args, "--branches", "--remotes")
+
+		count, err := commander.Exec(exec.Command("git", args...))
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(count) != "0" {
+			return fmt.Errorf("worktree %s has %s unpushed commits, use --force to remove it anyway", path, strings.TrimSpace(count))
+		}
+	}
+
+	repo := worktrees[0].Path
+	args := []string{"-C", repo, "worktree", "remove", path}
+	if force {
+		args = append(args, "--force")
+	}
+	if force && wt.Locked {
+		// git asks for a second --force to remove a locked worktree
+		args = append(args, "--force")
+	}
+	if _, err := commander.Exec(exec.Command("git", args...)); err != nil {
+		return err
+	}
+
+	if !deleteBranch {
+		return nil
+	}
+
+	flag := "-d"
+	if force {
+		flag = "-D"
+	}
+	_, err = commander.Exec(exec.Command("git", "-C", repo, "branch", flag, wt.Branch))
+	return err
+}
+
+// worktreeRoot returns the directory whose repository holds the worktrees of
+// the config: its root, or the current directory.
+func worktreeRoot(config *Config) (string, error) {
+	root := ExpandPath(config.Root)
+	if root != "" {
+		return root, nil
+	}
+
+	return os.Getwd()
+}
+
+// WorktreeInfo describes a worktree of a project and the state of its session.
+type WorktreeInfo struct {
+	Branch string `json:"branch"`
+	Head   string `json:"head"`
+	Path   string `json:"path"`
+	Dirty  bool   `json:"dirty"`
+	Locked bool   `json:"locked,omitempty"`
+	// Session is the running session of the worktree, or the one --worktree
+	// would start
+	Session string `json:"session"`
+	Running bool   `json:"running"`
+}
+
+// ListWorktrees describes the worktrees of the repository of the config, but
+// the bare repository and the prunable worktrees. The session of the config
+// itself counts for the worktree it's rooted in.
+func (smug Smug) ListWorktrees(config *Config) ([]WorktreeInfo, error) {
+	root, err := worktreeRoot(config)
+	if err != nil {
+		return nil, err
+	}
+
+	worktrees, err := GitWorktrees(smug.commander, root)
+	if err != nil {
+		return nil, err
+	}
+
+	// Listing fails when the server is not running
+	sessions, _ := smug.forConfig(config).tmux.ListSessions()
+
+	infos := []WorktreeInfo{}
 	for _, wt := range worktrees {
-		if wt.Branch == name || filepath.Base(wt.Path) == name {
-			return wt.Path, nil
+		if !wt.checkedOut() {
+			continue
 		}
+
+		info := WorktreeInfo{
+			Branch:  wt.Branch,
+			Head:    wt.Head,
+			Path:    wt.Path,
+			Locked:  wt.Locked,
+			Session: WorktreeSession(config.Session, wt),
+		}
+
+		status, err := smug.commander.Exec(exec.Command("git", "-C", wt.Path, "status", "--porcelain"))
+		info.Dirty = err == nil && status != ""
+
+		switch {
+		case slices.Contains(sessions, info.Session):
+			info.Running = true
+		case filepath.Clean(root) == wt.Path && slices.Contains(sessions, config.Session):
+			info.Session = config.Session
+			info.Running = true
+		}
+
+		infos = append(infos, info)
+	}
+
+	return infos, nil
+}
+
+// PrintWorktrees writes the worktrees as a table.
+func PrintWorktrees(w io.Writer, worktrees []WorktreeInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "BRANCH\tSTATUS\tSESSION\tPATH")
+
+	for _, wt := range worktrees {
+		branch := wt.Branch
+		if branch == "" {
+			branch = "(detached " + shortCommit(wt.Head) + ")"
+		}
+
+		status := "clean"
+		if wt.Dirty {
+			status = "dirty"
+		}
+		if wt.Locked {
+			status += ", locked"
+		}
+
+		session := "-"
+		if wt.Running {
+			session = wt.Session
+		}
+
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", branch, status, session, wt.Path)
 	}
 
-	return "", fmt.Errorf("worktree %q not found", name)
+	return tw.Flush()
 }
@@ -1,6 +1,12 @@
 package main
 
-import "testing"
+import (
+	"errors"
+	"os/exec"
+	"slices"
+	"strings"
+	"testing"
+)
 
 const worktreeListOutput = `worktree /home/ivan/dev/smug
 HEAD 7ac59da0000000000000000000000000000000000
@@ -15,47 +21,400 @@ HEAD def4560000000000000000000000000000000000
 detached
 `
 
+const bareWorktreeListOutput = `worktree /home/ivan/dev/api.git
+bare
+
+worktree /home/ivan/dev/api-main
+HEAD 1111111111111111111111111111111111111111
+branch refs/heads/main
+
+worktree /home/ivan/dev/api-feature-login
+HEAD 2222222222222222222222222222222222222222
+branch refs/heads/feature/login
+locked reviewing
+
+worktree /home/ivan/dev/api-feature-logout
+HEAD 3333333333333333333333333333333333333333
+branch refs/heads/feature/logout
+
+worktree /home/ivan/dev/api-hotfix
+HEAD 4444444444444444444444444444444444444444
+branch refs/heads/hotfix
+prunable gitdir file points to non-existent location
+
+worktree /home/ivan/dev/api-bisect
+HEAD 5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c2d3e4f
+detached
+`
+
 func TestParseWorktrees(t *testing.T) {
+	tests := []struct {
+		out      string
+		expected []Worktree
+	}{
+		{
+			worktreeListOutput,
+			[]Worktree{
+				{Path: "/home/ivan/dev/smug", Branch: "master", Head: "7ac59da0000000000000000000000000000000000"},
+				{Path: "/home/ivan/dev/smug-feature", Branch: "feature-x", Head: "abc1230000000000000000000000000000000000"},
+				{Path: "/home/ivan/dev/smug-detached", Branch: "", Head: "def4560000000000000000000000000000000000"},
+			},
+		},
+		{
+			bareWorktreeListOutput,
+			[]Worktree{
+				{Path: "/home/ivan/dev/api.git", Bare: true},
+				{Path: "/home/ivan/dev/api-main", Branch: "main", Head: "1111111111111111111111111111111111111111"},
+				{Path: "/home/ivan/dev/api-feature-login", Branch: "feature/login", Head: "2222222222222222222222222222222222222222", Locked: true},
+				{Path: "/home/ivan/dev/api-feature-logout", Branch: "feature/logout", Head: "3333333333333333333333333333333333333333"},
+				{Path: "/home/ivan/dev/api-hotfix", Branch: "hotfix", Head: "4444444444444444444444444444444444444444", Prunable: true},
+				{Path: "/home/ivan/dev/api-bisect", Head: "5e6f7a8b9c0d1e2f3a4b5c6d7e8f9a0b1c2d3e4f"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		worktrees := parseWorktrees(tt.out)
+
+		if len(worktrees) != len(tt.expected) {
+			t.Fatalf("expected %d worktrees, got %d", len(tt.expected), len(worktrees))
+		}
+
+		for i, wt := range worktrees {
+			if wt != tt.expected[i] {
+				t.Errorf("expected %+v, got %+v", tt.expected[i], wt)
+			}
+		}
+	}
+}
+
+func TestFindWorktree(t *testing.T) {
 	worktrees := parseWorktrees(worktreeListOutput)
+	bare := parseWorktrees(bareWorktreeListOutput)
 
-	expected := []Worktree{
-		{Path: "/home/ivan/dev/smug", Branch: "master"},
-		{Path: "/home/ivan/dev/smug-feature", Branch: "feature-x"},
-		{Path: "/home/ivan/dev/smug-detached", Branch: ""},
+	tests := []struct {
+		worktrees []Worktree
+		name      string
+		want      string
+		wantErr   bool
+	}{
+		{worktrees, "feature-x", "/home/ivan/dev/smug-feature", false},    // by branch
+		{worktrees, "smug-feature", "/home/ivan/dev/smug-feature", false}, // by directory basename
+		{worktrees, "smug-detached", "/home/ivan/dev/smug-detached", false},
+		{worktrees, "/home/ivan/dev/smug-feature", "/home/ivan/dev/smug-feature", false}, // by path
+		{worktrees, "missing", "", true},
+		{bare, "main", "/home/ivan/dev/api-main", false},
+		{bare, "5e6f", "/home/ivan/dev/api-bisect", false},                 // by commit
+		{bare, "feature/logo", "/home/ivan/dev/api-feature-logout", false}, // by prefix
+		{bare, "flogout", "/home/ivan/dev/api-feature-logout", false},      // fuzzy
+		{bare, "feature/", "", true},                                       // ambiguous prefix
+		{bare, "fl", "", true},                                             // ambiguous fuzzy match
+		{bare, "api.git", "", true},                                        // bare repository
+		{bare, "hotfix", "", true},                                         // prunable
 	}
 
-	if len(worktrees) != len(expected) {
-		t.Fatalf("expected %d worktrees, got %d", len(expected), len(worktrees))
+	for _, tt := range tests {
+		got, err := FindWorktree(tt.worktrees, tt.name)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("FindWorktree(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+		}
+		if got.Path != tt.want {
+			t.Errorf("FindWorktree(%q) = %q, want %q", tt.name, got.Path, tt.want)
+		}
 	}
 
-	for i, wt := range worktrees {
-		if wt != expected[i] {
-			t.Errorf("expected %+v, got %+v", expected[i], wt)
+	_, err := FindWorktree(bare, "feature/")
+	if err == nil || !strings.Contains(err.Error(), "feature/login, feature/logout") {
+		t.Errorf("expected the ambiguity error to name the candidates, got %v", err)
+	}
+}
+
+func TestWorktreeSession(t *testing.T) {
+	tests := []struct {
+		wt   Worktree
+		want string
+	}{
+		{Worktree{Path: "/home/ivan/dev/smug-feature", Branch: "feature-x"}, "blog/feature-x"},
+		{Worktree{Path: "/home/ivan/dev/smug-fix", Branch: "fix/v1.2:crash"}, "blog/fix/v1_2_crash"},
+		{Worktree{Path: "/home/ivan/dev/smug-detached"}, "blog/smug-detached"},
+	}
+
+	for _, tt := range tests {
+		if got := WorktreeSession("blog", tt.wt); got != tt.want {
+			t.Errorf("WorktreeSession(%+v) = %q, want %q", tt.wt, got, tt.want)
 		}
 	}
 }
 
-func TestFindWorktree(t *testing.T) {
-	worktrees := parseWorktrees(worktreeListOutput)
+func TestWorktreePath(t *testing.T) {
+	main := Worktree{Path: "/home/ivan/dev/smug"}
+	bare := Worktree{Path: "/home/ivan/dev/api.git", Bare: true}
 
 	tests := []struct {
-		name    string
+		pattern string
+		main    Worktree
+		branch  string
+		want    string
+	}{
+		{"", main, "feature-x", "/home/ivan/dev/smug-feature-x"},
+		{"", main, "fix/crash", "/home/ivan/dev/smug-fix-crash"},
+		{".worktrees/{branch}", main, "feature-x", "/home/ivan/dev/smug/.worktrees/feature-x"},
+		{"/tmp/{name}/{branch}", main, "feature-x", "/tmp/smug/feature-x"},
+		{"", bare, "feature-x", "/home/ivan/dev/feature-x"},
+		{"../{name}-{branch}", bare, "feature-x", "/home/ivan/dev/api-feature-x"},
+	}
+
+	for _, tt := range tests {
+		if got := WorktreePath(tt.pattern, tt.main, tt.branch); got != tt.want {
+			t.Errorf("WorktreePath(%q, %q) = %q, want %q", tt.pattern, tt.branch, got, tt.want)
+		}
+	}
+}
+
+// gitCommander answers git commands for a repository with the given local
+// branches, and the outputs of the commands containing the keys of outputs.
+type gitCommander struct {
+	branches []string
+	outputs  map[string]string
+	commands []string
+}
+
+func (c *gitCommander) Exec(cmd *exec.Cmd) (string, error) {
+	command := strings.Join(cmd.Args, " ")
+	c.commands = append(c.commands, command)
+
+	if slices.Contains(cmd.Args, "rev-parse") {
+		ref := cmd.Args[len(cmd.Args)-1]
+		if !slices.Contains(c.branches, strings.TrimPrefix(ref, "refs/heads/")) {
+			return "", errors.New("exit status 1")
+		}
+	}
+
+	for key, output := range c.outputs {
+		if strings.Contains(command, key) {
+			return output, nil
+		}
+	}
+
+	return "", nil
+}
+
+func (c *gitCommander) ExecSilently(cmd *exec.Cmd) error {
+	_, err := c.Exec(cmd)
+	return err
+}
+
+func TestAddWorktree(t *testing.T) {
+	tests := []struct {
+		branch  string
+		from    string
+		remotes string
 		want    string
 		wantErr bool
 	}{
-		{"feature-x", "/home/ivan/dev/smug-feature", false},    // by branch
-		{"smug-feature", "/home/ivan/dev/smug-feature", false}, // by directory basename
-		{"smug-detached", "/home/ivan/dev/smug-detached", false},
-		{"missing", "", true},
+		{"feature-x", "", "", "git -C /repo worktree add /wt feature-x", false},
+		{"feature-y", "", "", "git -C /repo worktree add -b feature-y /wt", false},
+		{"feature-y", "main", "", "git -C /repo worktree add -b feature-y /wt main", false},
+		{"feature-x", "main", "", "", true},
+		{"feature-z", "", "origin/feature-z", "git -C /repo worktree add --track -b feature-z /wt origin/feature-z", false},
+		{"feature-z", "", "origin/feature-z\nfork/feature-z", "git -C /repo worktree add -b feature-z /wt", false},
+		{"feature-z", "main", "origin/feature-z", "git -C /repo worktree add -b feature-z /wt main", false},
 	}
 
 	for _, tt := range tests {
-		got, err := FindWorktree(worktrees, tt.name)
+		commander := &gitCommander{
+			branches: []string{"main", "feature-x"},
+			outputs:  map[string]string{"for-each-ref": tt.remotes},
+		}
+		err := AddWorktree(commander, "/repo", "/wt", tt.branch, tt.from)
 		if (err != nil) != tt.wantErr {
-			t.Errorf("FindWorktree(%q) error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			t.Errorf("AddWorktree(%q, %q) error = %v, wantErr %v", tt.branch, tt.from, err, tt.wantErr)
+			continue
+		}
+
+		added := ""
+		for _, command := range commander.commands {
+			if strings.Contains(command, "worktree add") {
+				added = command
+			}
+		}
+		if added != tt.want {
+			t.Errorf("AddWorktree(%q, %q) ran %q, want %q", tt.branch, tt.from, added, tt.want)
+		}
+	}
+}
+
+func TestRemoveWorktree(t *testing.T) {
+	tests := []struct {
+		name         string
+		path         string
+		status       string
+		unpushed     string
+		deleteBranch bool
+		force        bool
+		want         []string
+		wantErr      bool
+	}{
+		{
+			name:     "clean",
+			path:     "/home/ivan/dev/smug-feature",
+			unpushed: "0",
+			want:     []string{"git -C /home/ivan/dev/smug worktree remove /home/ivan/dev/smug-feature"},
+		},
+		{
+			name:         "with its branch",
+			path:         "/home/ivan/dev/smug-feature",
+			unpushed:     "0",
+			deleteBranch: true,
+			want: []string{
+				"git -C /home/ivan/dev/smug worktree remove /home/ivan/dev/smug-feature",
+				"git -C /home/ivan/dev/smug branch -d feature-x",
+			},
+		},
+		{
+			name:     "uncommitted changes",
+			path:     "/home/ivan/dev/smug-feature",
+			status:   " M main.go",
+			unpushed: "0",
+			wantErr:  true,
+		},
+		{
+			name:     "unpushed commits",
+			path:     "/home/ivan/dev/smug-feature",
+			unpushed: "2",
+			wantErr:  true,
+		},
+		{
+			name:         "forced",
+			path:         "/home/ivan/dev/smug-feature",
+			status:       " M main.go",
+			unpushed:     "2",
+			deleteBranch: true,
+			force:        true,
+			want: []string{
+				"git -C /home/ivan/dev/smug worktree remove /home/ivan/dev/smug-feature --force",
+				"git -C /home/ivan/dev/smug branch -D feature-x",
+			},
+		},
+		{
+			name:         "detached with its branch",
+			path:         "/home/ivan/dev/smug-detached",
+			unpushed:     "0",
+			deleteBranch: true,
+			wantErr:      true,
+		},
+		{
+			name:    "main worktree",
+			path:    "/home/ivan/dev/smug",
+			force:   true,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		commander := &gitCommander{outputs: map[string]string{
+			"worktree list": worktreeListOutput,
+			"status":        tt.status,
+			"rev-list":      tt.unpushed,
+		}}
+
+		err := RemoveWorktree(commander, tt.path, tt.deleteBranch, tt.force)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s: error = %v, wantErr %v", tt.name, err, tt.wantErr)
+			continue
+		}
+
+		var removed []string
+		for _, command := range commander.commands {
+			if strings.Contains(command, "worktree remove") || strings.Contains(command, " branch ") {
+				removed = append(removed, command)
+			}
+		}
+		if !slices.Equal(removed, tt.want) {
+			t.Errorf("%s: ran %v, want %v", tt.name, removed, tt.want)
+		}
+	}
+}
+
+func TestListWorktrees(t *testing.T) {
+	commander := &gitCommander{outputs: map[string]string{
+		"worktree list":       worktreeListOutput,
+		"smug-feature status": " M main.go",
+		"list-sessions":       "blog\nblog/feature-x\nother",
+	}}
+	smug := Smug{Tmux{commander, &TmuxOptions{}}, commander}
+
+	worktrees, err := smug.ListWorktrees(&Config{Session: "blog", Root: "/home/ivan/dev/smug"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []WorktreeInfo{
+		{Branch: "master", Head: "7ac59da0000000000000000000000000000000000", Path: "/home/ivan/dev/smug", Session: "blog", Running: true},
+		{Branch: "feature-x", Head: "abc1230000000000000000000000000000000000", Path: "/home/ivan/dev/smug-feature", Dirty: true, Session: "blog/feature-x", Running: true},
+		{Path: "/home/ivan/dev/smug-detached", Head: "def4560000000000000000000000000000000000", Session: "blog/smug-detached"},
+	}
+	if !slices.Equal(worktrees, expected) {
+		t.Errorf("expected %+v, got %+v", expected, worktrees)
+	}
+}
+
+func TestPrintWorktrees(t *testing.T) {
+	var out strings.Builder
+	err := PrintWorktrees(&out, []WorktreeInfo{
+		{Branch: "master", Path: "/dev/smug", Session: "blog", Running: true},
+		{Path: "/dev/smug-detached", Head: "def4560000000000000000000000000000000000", Dirty: true, Locked: true, Session: "blog/smug-detached"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `BRANCH              STATUS         SESSION  PATH
+master              clean          blog     /dev/smug
+(detached def4560)  dirty, locked  -        /dev/smug-detached
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+}
+
+func TestWorktreeConfigs(t *testing.T) {
+	commander := &gitCommander{outputs: map[string]string{"worktree list": bareWorktreeListOutput}}
+
+	tests := []struct {
+		pattern  string
+		sessions []string
+		wantErr  bool
+	}{
+		{"*", []string{"api/main", "api/feature/login", "api/feature/logout", "api/api-bisect"}, false},
+		{"feature/*", []string{"api/feature/login", "api/feature/logout"}, false},
+		{"m*", []string{"api/main"}, false},
+		{"release/*", nil, true},
+		{"[", nil, true},
+	}
+
+	for _, tt := range tests {
+		config := &Config{Session: "api", Root: "/home/ivan/dev/api.git", Env: map[string]string{"SMUG_SESSION": "api"}}
+
+		configs, err := WorktreeConfigs(config, tt.pattern, commander)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("WorktreeConfigs(%q) error = %v, wantErr %v", tt.pattern, err, tt.wantErr)
+			continue
+		}
+
+		var sessions []string
+		for _, c := range configs {
+			sessions = append(sessions, c.Session)
+			if c.Env["SMUG_SESSION"] != c.Session || c.Env["SMUG_WORKTREE"] != c.Root {
+				t.Errorf("WorktreeConfigs(%q): unexpected env %v for %s", tt.pattern, c.Env, c.Root)
+			}
+		}
+		if !slices.Equal(sessions, tt.sessions) {
+			t.Errorf("WorktreeConfigs(%q) = %v, want %v", tt.pattern, sessions, tt.sessions)
 		}
-		if got != tt.want {
-			t.Errorf("FindWorktree(%q) = %q, want %q", tt.name, got, tt.want)
+
+		if config.Session != "api" || config.Env["SMUG_SESSION"] != "api" {
+			t.Errorf("WorktreeConfigs(%q) changed the config", tt.pattern)
 		}
 	}
 }